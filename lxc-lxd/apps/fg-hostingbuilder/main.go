@@ -2,91 +2,88 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
-	"github.com/joho/godotenv"
-
 	"sharedmodule"
+	"sharedmodule/config"
 
 	"huaweicloud.com/go-runtime/events/smn"
 	fgcontext "huaweicloud.com/go-runtime/go-api/context"
 	"huaweicloud.com/go-runtime/pkg/runtime"
+	fgcommon "huaweicloud.com/go-runtime/pkg/runtime/common"
+	"huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
+	"huaweicloud.com/go-runtime/pkg/runtime/redact"
 )
 
 //go:embed kubernetes-templates/*
 var kubernetesTemplates embed.FS
 
+// Config holds no access keys: they come from ctx.Credentials(), i.e. the
+// temporary keys of the function's agency, falling back to the ACCESS_KEY
+// and SECRET_KEY variables.
 type Config struct {
-	HostingBuilderImage     string
-	ImagePullPolicy         string
-	AccessKey               string
-	SecretKey               string
-	ProjectName             string
-	DependencyPath          string
-	PrintOutFile            bool
-	K8sNamespace            string
-	CciIamAuthenticatorPath string
-	KubectlPath             string
-	LxdHost                 string
-	AnsibleUser             string
-	AnsiblePassword         string
-	DbRootHost              string
-	DbRootUser              string
-	DbRootPassword          string
-	TopicUrn                string
+	HostingBuilderImage     string `env:"HOSTING_BUILDER_IMAGE" default:"swr.ap-southeast-4.myhuaweicloud.com/demo-huawei/hostingbuilder:latest"`
+	ImagePullPolicy         string `env:"IMAGE_PULL_POLICY" default:"IfNotPresent"`
+	ProjectName             string `env:"PROJECT_NAME" default:"ap-southeast-4"`
+	DependencyPath          string `env:"DEPENDENCY_PATH" default:"./code"`
+	PrintOutFile            bool   `env:"PRINT_OUT_FILE" default:"false"`
+	K8sNamespace            string `env:"K8S_NAMESPACE" default:"default"`
+	CciIamAuthenticatorPath string `env:"CCI_IAM_AUTHENTICATOR_PATH"`
+	KubectlPath             string `env:"KUBECTL_PATH"`
+	LxdHost                 string `env:"LXD_HOST" required:"true"`
+	AnsibleUser             string `env:"ANSIBLE_USER" default:"root"`
+	AnsiblePassword         string `env:"ANSIBLE_PASSWORD" secret:"true"`
+	DbRootHost              string `env:"DB_ROOT_HOST" default:"localhost"`
+	DbRootUser              string `env:"DB_ROOT_USER" default:"root"`
+	DbRootPassword          string `env:"DB_ROOT_PASSWORD" secret:"true"`
+	TopicUrn                string `env:"TOPIC_URN" required:"true"`
 }
 
 var appConfig Config
 
-func loadConfig() {
-	if err := godotenv.Load(); err != nil {
-		slog.Warn("failed to load .env file", slog.String("error", err.Error()))
+func loadConfig() error {
+	err := config.Load(&appConfig, config.WithDotEnv())
+
+	// The generated job carries these, keep them out of logs and errors.
+	redact.LoadEnvSecrets()
+	redact.AddSecrets(config.Secrets(&appConfig)...)
+	if err != nil {
+		return err
 	}
 
-	appConfig.HostingBuilderImage = sharedmodule.GetEnv("HOSTING_BUILDER_IMAGE", "swr.ap-southeast-4.myhuaweicloud.com/demo-huawei/hostingbuilder:latest")
-	appConfig.ImagePullPolicy = sharedmodule.GetEnv("IMAGE_PULL_POLICY", "IfNotPresent")
-	appConfig.AccessKey = sharedmodule.GetEnv("ACCESS_KEY", "")
-	appConfig.SecretKey = sharedmodule.GetEnv("SECRET_KEY", "")
-	appConfig.ProjectName = sharedmodule.GetEnv("PROJECT_NAME", "ap-southeast-4")
-	appConfig.DependencyPath = sharedmodule.GetEnv("DEPENDENCY_PATH", "./code")
-	appConfig.PrintOutFile, _ = strconv.ParseBool(sharedmodule.GetEnv("PRINT_OUT_FILE", "false"))
-	appConfig.K8sNamespace = sharedmodule.GetEnv("K8S_NAMESPACE", "default")
-	appConfig.CciIamAuthenticatorPath = sharedmodule.GetEnv("CCI_IAM_AUTHENTICATOR_PATH", "")
-	appConfig.KubectlPath = sharedmodule.GetEnv("KUBECTL_PATH", "")
-	appConfig.LxdHost = sharedmodule.GetEnv("LXD_HOST", "")
-	appConfig.AnsibleUser = sharedmodule.GetEnv("ANSIBLE_USER", "root")
-	appConfig.AnsiblePassword = sharedmodule.GetEnv("ANSIBLE_PASSWORD", "")
-	appConfig.DbRootHost = sharedmodule.GetEnv("DB_ROOT_HOST", "localhost")
-	appConfig.DbRootUser = sharedmodule.GetEnv("DB_ROOT_USER", "root")
-	appConfig.DbRootPassword = sharedmodule.GetEnv("DB_ROOT_PASSWORD", "")
-	appConfig.TopicUrn = sharedmodule.GetEnv("TOPIC_URN", "")
+	slog.Info("configuration loaded", slog.Any("config", config.Masked(&appConfig)))
+	return nil
 }
 
-func getCCIToken() (string, error) {
+func getCCIToken(ctx context.Context, creds fgcommon.Credentials) (string, error) {
 
 	cciIamAuthenticatorPath := appConfig.DependencyPath + "/cci-iam-authenticator"
 	if appConfig.CciIamAuthenticatorPath != "" {
 		cciIamAuthenticatorPath = appConfig.CciIamAuthenticatorPath
 	}
 
-	cmd := exec.Command(cciIamAuthenticatorPath,
+	cmd := exec.CommandContext(ctx, cciIamAuthenticatorPath,
 		"token",
 		"--iam-endpoint=https://iam.myhuaweicloud.com",
 		"--insecure-skip-tls-verify=true",
 		"--cache=false",
 		"--token-only=true",
 		"--project-name="+appConfig.ProjectName,
-		"--ak="+appConfig.AccessKey,
-		"--sk="+appConfig.SecretKey,
+		"--ak="+creds.AK,
+		"--sk="+creds.SK,
 	)
 
 	out, err := cmd.CombinedOutput()
@@ -98,14 +95,14 @@ func getCCIToken() (string, error) {
 	return token, nil
 }
 
-func applyK8s(token string, yamlContent []byte) error {
+func applyK8s(ctx context.Context, token string, yamlContent []byte) error {
 
 	kubectlPath := appConfig.DependencyPath + "/kubectl"
 	if appConfig.KubectlPath != "" {
 		kubectlPath = appConfig.KubectlPath
 	}
 
-	cmd := exec.Command(kubectlPath,
+	cmd := exec.CommandContext(ctx, kubectlPath,
 		"--token="+token,
 		"--server=https://cci."+appConfig.ProjectName+".myhuaweicloud.com",
 		"--insecure-skip-tls-verify=true",
@@ -120,7 +117,7 @@ func applyK8s(token string, yamlContent []byte) error {
 		return fmt.Errorf("kubectl error: %v\nOutput: %s", err, string(out))
 	}
 
-	fmt.Println(string(out))
+	fmt.Println(redact.String(string(out)))
 	return nil
 }
 
@@ -131,13 +128,13 @@ type JobStatus struct {
 	Phase     string `json:"phase,omitempty"`
 }
 
-func getJobStatus(token string, jobName string) (*JobStatus, error) {
+func getJobStatus(ctx context.Context, token string, jobName string) (*JobStatus, error) {
 	kubectlPath := appConfig.DependencyPath + "/kubectl"
 	if appConfig.KubectlPath != "" {
 		kubectlPath = appConfig.KubectlPath
 	}
 
-	cmd := exec.Command(kubectlPath,
+	cmd := exec.CommandContext(ctx, kubectlPath,
 		"--token="+token,
 		"--server=https://cci."+appConfig.ProjectName+".myhuaweicloud.com",
 		"--insecure-skip-tls-verify=true",
@@ -204,14 +201,14 @@ func getJobStatus(token string, jobName string) (*JobStatus, error) {
 	return status, nil
 }
 
-func isJobComplete(token string, jobName string) (bool, bool, error) {
+func isJobComplete(ctx context.Context, token string, jobName string) (bool, bool, error) {
 	kubectlPath := appConfig.DependencyPath + "/kubectl"
 	if appConfig.KubectlPath != "" {
 		kubectlPath = appConfig.KubectlPath
 	}
 
 	// Check job conditions using a more reliable approach
-	cmd := exec.Command(kubectlPath,
+	cmd := exec.CommandContext(ctx, kubectlPath,
 		"--token="+token,
 		"--server=https://cci."+appConfig.ProjectName+".myhuaweicloud.com",
 		"--insecure-skip-tls-verify=true",
@@ -261,7 +258,7 @@ func isJobComplete(token string, jobName string) (bool, bool, error) {
 	return complete, failed, nil
 }
 
-func waitK8sJobCompletion(token string, jobName string) error {
+func waitK8sJobCompletion(ctx context.Context, token string, jobName string) error {
 	const (
 		maxWaitTime     = 10 * time.Minute // Maximum wait time
 		pollingInterval = 5 * time.Second  // Check every 5 seconds
@@ -281,13 +278,13 @@ func waitK8sJobCompletion(token string, jobName string) error {
 			return fmt.Errorf("job %s timed out after %v", jobName, maxWaitTime)
 		}
 
-		status, err := getJobStatus(token, jobName)
+		status, err := getJobStatus(ctx, token, jobName)
 		if err != nil {
 			return err
 		}
 
 		// First try the more reliable condition-based approach
-		complete, failed, condErr := isJobComplete(token, jobName)
+		complete, failed, condErr := isJobComplete(ctx, token, jobName)
 		if condErr == nil {
 			if complete {
 				fmt.Printf("Job %s completed successfully (via conditions)\n", jobName)
@@ -295,7 +292,7 @@ func waitK8sJobCompletion(token string, jobName string) error {
 			}
 			if failed {
 				// Get more detailed error information
-				cmd := exec.Command(kubectlPath,
+				cmd := exec.CommandContext(ctx, kubectlPath,
 					"--token="+token,
 					"--server=https://cci."+appConfig.ProjectName+".myhuaweicloud.com",
 					"--insecure-skip-tls-verify=true",
@@ -316,7 +313,7 @@ func waitK8sJobCompletion(token string, jobName string) error {
 
 		if status.Failed > 0 {
 			// Get more detailed error information
-			cmd := exec.Command(kubectlPath,
+			cmd := exec.CommandContext(ctx, kubectlPath,
 				"--token="+token,
 				"--server=https://cci."+appConfig.ProjectName+".myhuaweicloud.com",
 				"--insecure-skip-tls-verify=true",
@@ -340,12 +337,16 @@ func waitK8sJobCompletion(token string, jobName string) error {
 			fmt.Printf("Job %s is still running (active: %d)...\n", jobName, status.Active)
 		}
 
-		// Wait before next poll
-		time.Sleep(pollingInterval)
+		// Wait before next poll, giving up when the function times out
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for job %s: %w", jobName, ctx.Err())
+		case <-time.After(pollingInterval):
+		}
 	}
 }
 
-func generateK8sJob(smnMessage sharedmodule.HostingDetail) ([]byte, error) {
+func generateK8sJob(smnMessage sharedmodule.HostingDetail, creds fgcommon.Credentials) ([]byte, error) {
 
 	tmpl, err := template.ParseFS(kubernetesTemplates, "kubernetes-templates/hostingbuilder.yaml")
 	if err != nil {
@@ -367,8 +368,9 @@ func generateK8sJob(smnMessage sharedmodule.HostingDetail) ([]byte, error) {
 		"DB_ROOT_USER":        appConfig.DbRootUser,
 		"DB_ROOT_PASSWORD":    appConfig.DbRootPassword,
 		"WORDPRESS_THEME":     string(smnMessage.Theme),
-		"ACCESS_KEY":          appConfig.AccessKey,
-		"SECRET_KEY":          appConfig.SecretKey,
+		"ACCESS_KEY":          creds.AK,
+		"SECRET_KEY":          creds.SK,
+		"SECURITY_TOKEN":      creds.SecurityToken,
 		"TOPIC_URN":           appConfig.TopicUrn,
 	}); err != nil {
 		return nil, fmt.Errorf("failed to execute template: %w", err)
@@ -392,42 +394,73 @@ func generateK8sJob(smnMessage sharedmodule.HostingDetail) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// cciTokenTTL is how long a token from cci-iam-authenticator is reused, IAM
+// tokens are valid for 24 hours.
+const cciTokenTTL = 12 * time.Hour
+
 type handler struct {
-	K8sToken string
+	mu              sync.Mutex
+	cciToken        string
+	cciTokenCreds   fgcommon.Credentials
+	cciTokenExpires time.Time
+}
+
+// k8sToken returns the token kubectl authenticates with in this invocation.
+// With the temporary keys of an agency it is the agency's IAM token sent with
+// the invocation, since cci-iam-authenticator only takes permanent keys.
+// Otherwise it is a token from cci-iam-authenticator, reused for cciTokenTTL
+// as long as the keys do not change.
+func (h *handler) k8sToken(ctx fgcontext.RuntimeContext, creds fgcommon.Credentials) (string, error) {
+	if token := ctx.GetToken(); creds.Temporary() && token != "" {
+		return token, nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cciToken != "" && h.cciTokenCreds == creds && time.Now().Before(h.cciTokenExpires) {
+		return h.cciToken, nil
+	}
+	token, err := getCCIToken(ctx.GetContext(), creds)
+	if err != nil {
+		return "", fmt.Errorf("failed to get CCI token: %w", err)
+	}
+	h.cciToken, h.cciTokenCreds, h.cciTokenExpires = token, creds, time.Now().Add(cciTokenTTL)
+	return token, nil
 }
 
-func (h *handler) SmnTrigger(payload []byte, ctx fgcontext.RuntimeContext) (interface{}, error) {
-	var smnEvent smn.SMNTriggerEvent
-	err := json.Unmarshal(payload, &smnEvent)
+func (h *handler) SmnTrigger(fnCtx context.Context, smnEvent smn.SMNTriggerEvent, ctx fgcontext.RuntimeContext) (string, error) {
+	// Retrieved on every invocation, temporary keys expire.
+	creds, err := ctx.Credentials().Retrieve()
+	if err != nil {
+		return "", err
+	}
+	token, err := h.k8sToken(ctx, creds)
 	if err != nil {
-		slog.Error("unmarshal payload failed")
-		return "invalid data", err
+		return "", err
 	}
-	ctx.GetLogger().Logf("payload:%s", smnEvent.String())
+	// The keys and token rotate, so they are masked for this invocation
+	// only instead of being registered with redact.AddSecrets.
+	scrubber := redact.NewScrubber(creds.SK, creds.SecurityToken, token)
 
 	var c int = 1
 	for _, record := range smnEvent.Record {
 		var smnMessage sharedmodule.HostingDetail
-		err = json.Unmarshal([]byte(record.Smn.Message), &smnMessage)
-		if err != nil {
+		if err := json.Unmarshal([]byte(record.Smn.Message), &smnMessage); err != nil {
 			slog.Error("unmarshal record failed")
-			return "invalid data", err
+			return "invalid data", fnhandler.NewUserError(http.StatusBadRequest, fnhandler.ErrorTypeValidation, "invalid smn message: "+err.Error(), nil)
 		}
 
-		yamlContent, err := generateK8sJob(smnMessage)
+		yamlContent, err := generateK8sJob(smnMessage, creds)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			return nil, err
+			return "", scrubError(scrubber, err)
 		}
 
-		if err := applyK8s(h.K8sToken, yamlContent); err != nil {
-			fmt.Printf("Error: %v\n", err)
-			return nil, err
+		if err := applyK8s(fnCtx, token, yamlContent); err != nil {
+			return "", scrubError(scrubber, err)
 		}
 
-		if err := waitK8sJobCompletion(h.K8sToken, "hb-"+smnMessage.SubDomain); err != nil {
-			fmt.Printf("Error: %v\n", err)
-			return nil, err
+		if err := waitK8sJobCompletion(fnCtx, token, "hb-"+smnMessage.SubDomain); err != nil {
+			return "", scrubError(scrubber, err)
 		}
 
 		slog.Info(fmt.Sprintf("hosting builder job created #%d for %s", c, smnMessage.SubDomain))
@@ -447,18 +480,39 @@ func (h *handler) SmnTrigger(payload []byte, ctx fgcontext.RuntimeContext) (inte
 	return "ok", nil
 }
 
-func main() {
-
-	loadConfig()
+// scrubError prints err with the secrets of the invocation masked and
+// returns it masked as well, since the runtime logs the returned error.
+func scrubError(scrubber redact.Scrubber, err error) error {
+	message := scrubber.String(err.Error())
+	fmt.Printf("Error: %v\n", message)
+	return errors.New(message)
+}
 
-	token, err := getCCIToken()
+// Initialize checks the credentials and fetches the first CCI token before
+// the first invocation, so an instance without working keys fails early.
+func (h *handler) Initialize(ctx fgcontext.RuntimeContext) error {
+	creds, err := ctx.Credentials().Retrieve()
 	if err != nil {
-		slog.Error("failed to get CCI token", slog.String("error", err.Error()))
+		return err
+	}
+	_, err = h.k8sToken(ctx, creds)
+	return err
+}
+
+func main() {
+
+	slog.SetDefault(slog.New(redact.NewHandler(slog.NewTextHandler(os.Stderr, nil))))
+	if err := loadConfig(); err != nil {
+		slog.Error("failed to load configuration", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 
-	h := &handler{
-		K8sToken: token,
+	h := &handler{}
+
+	runtime.Use(fnhandler.RequestLogging())
+	runtime.RegisterInitializer(h.Initialize)
+	if err := runtime.RegisterTyped(h.SmnTrigger); err != nil {
+		slog.Error("function runtime stopped", slog.String("error", err.Error()))
+		os.Exit(runtime.ExitCode(err))
 	}
-	runtime.Register(h.SmnTrigger)
 }
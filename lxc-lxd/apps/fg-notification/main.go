@@ -1,53 +1,53 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"net/smtp"
 	"os"
 	"sharedmodule"
-	"strconv"
+	"sharedmodule/config"
 
-	"github.com/joho/godotenv"
 	"huaweicloud.com/go-runtime/events/smn"
 	fgcontext "huaweicloud.com/go-runtime/go-api/context"
 	"huaweicloud.com/go-runtime/pkg/runtime"
+	"huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
 )
 
 type Config struct {
-	Local bool
-	Smtp  ConfigSmtp
+	Local bool `env:"LOCAL" default:"false"`
+	// LocalRecipient receives the test email sent in local mode, SMTP_EMAIL
+	// when empty.
+	LocalRecipient string `env:"LOCAL_RECIPIENT"`
+	Smtp           ConfigSmtp
 }
 
 type ConfigSmtp struct {
-	Host       string
-	Port       int
-	Email      string
-	Password   string
-	SenderName string
+	Host     string `env:"SMTP_HOST" required:"true"`
+	Port     int    `env:"SMTP_PORT" default:"587"`
+	Email    string `env:"SMTP_EMAIL" required:"true"`
+	Password string `env:"SMTP_PASSWORD" required:"true" secret:"true"`
+	// SenderName is the From header, SMTP_EMAIL when empty.
+	SenderName string `env:"SMTP_SENDER_NAME"`
 }
 
 var AppConfig Config
 
-func loadConfig() {
-	if err := godotenv.Load(); err != nil {
-		fmt.Printf("failed to load .env file: %s\n", err.Error())
+func loadConfig() error {
+	if err := config.Load(&AppConfig, config.WithDotEnv()); err != nil {
+		return err
 	}
-
-	AppConfig.Local, _ = strconv.ParseBool(getEnv("LOCAL", "false"))
-	AppConfig.Smtp.Host = getEnv("SMTP_HOST", "smtp.example.com")
-	AppConfig.Smtp.Port = 587
-	AppConfig.Smtp.Email = getEnv("SMTP_EMAIL", "dianraha11@gmail.com")
-	AppConfig.Smtp.Password = getEnv("SMTP_PASSWORD", "password")
-	AppConfig.Smtp.SenderName = getEnv("SMTP_SENDER_NAME", "Demo Huawei <dianraha11@gmail.com>")
-}
-
-func getEnv(key, defaultValue string) string {
-	if value, exist := os.LookupEnv(key); exist {
-		return value
+	if AppConfig.Smtp.SenderName == "" {
+		AppConfig.Smtp.SenderName = AppConfig.Smtp.Email
+	}
+	if AppConfig.LocalRecipient == "" {
+		AppConfig.LocalRecipient = AppConfig.Smtp.Email
 	}
-	return defaultValue
+	slog.Info("configuration loaded", slog.Any("config", config.Masked(&AppConfig)))
+	return nil
 }
 
 func sendEmail(to, subject, message string) error {
@@ -59,22 +59,13 @@ func sendEmail(to, subject, message string) error {
 	return smtp.SendMail(smtpAddr, auth, AppConfig.Smtp.Email, []string{to}, []byte(body))
 }
 
-func SmnTrigger(payload []byte, ctx fgcontext.RuntimeContext) (interface{}, error) {
-	var smnEvent smn.SMNTriggerEvent
-	err := json.Unmarshal(payload, &smnEvent)
-	if err != nil {
-		slog.Info("unmarshal payload failed")
-		return "invalid data", err
-	}
-	ctx.GetLogger().Logf("payload:%s", smnEvent.String())
-
+func SmnTrigger(_ context.Context, smnEvent smn.SMNTriggerEvent, ctx fgcontext.RuntimeContext) (string, error) {
 	var c int = 1
 	for _, record := range smnEvent.Record {
 		var n sharedmodule.Notification
-		err = json.Unmarshal([]byte(record.Smn.Message), &n)
-		if err != nil {
+		if err := json.Unmarshal([]byte(record.Smn.Message), &n); err != nil {
 			slog.Info("unmarshal notification failed")
-			return "invalid data", err
+			return "invalid data", fnhandler.NewUserError(http.StatusBadRequest, fnhandler.ErrorTypeValidation, "invalid smn message: "+err.Error(), nil)
 		}
 
 		slog.Info(fmt.Sprintf("notification #%d:%+v", c, n))
@@ -92,14 +83,21 @@ func SmnTrigger(payload []byte, ctx fgcontext.RuntimeContext) (interface{}, erro
 
 func main() {
 
-	loadConfig()
+	if err := loadConfig(); err != nil {
+		slog.Error("failed to load configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
 	if AppConfig.Local {
 		slog.Info("running in local mode")
-		if err := sendEmail("pipelinekirimuang@gmail.com", "Test Email", "This is a test email from local run"); err != nil {
-			slog.Error("failed to send test email", err.Error())
+		if err := sendEmail(AppConfig.LocalRecipient, "Test Email", "This is a test email from local run"); err != nil {
+			slog.Error("failed to send test email", slog.String("error", err.Error()))
 		}
 	} else {
-		runtime.Register(SmnTrigger)
+		runtime.Use(fnhandler.RequestLogging())
+		if err := runtime.RegisterTyped(SmnTrigger); err != nil {
+			slog.Error("function runtime stopped", slog.String("error", err.Error()))
+			os.Exit(runtime.ExitCode(err))
+		}
 	}
 }
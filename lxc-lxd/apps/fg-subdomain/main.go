@@ -1,117 +1,184 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
-	"github.com/joho/godotenv"
 	"huaweicloud.com/go-runtime/events/smn"
 	fgcontext "huaweicloud.com/go-runtime/go-api/context"
+	fgcommon "huaweicloud.com/go-runtime/pkg/runtime/common"
 
 	"sharedmodule"
+	"sharedmodule/config"
 
 	dns "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/dns/v2"
 	dnsModel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/dns/v2/model"
 	dnsRegion "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/dns/v2/region"
 	"huaweicloud.com/go-runtime/pkg/runtime"
+	"huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
 )
 
 type Config struct {
-	AccessKey   string
-	SecretKey   string
-	DnsZoneId   string
-	DnsRecordIp string
-	DnsZoneName string
+	DnsZoneId   string `env:"DNS_ZONE_ID" required:"true"`
+	DnsRecordIp string `env:"DNS_RECORD_IP" required:"true"`
+	DnsZoneName string `env:"DNS_ZONE_NAME" default:"onhuawei.cloud"`
 }
 
 var AppConfig Config
 
-func loadConfig() {
-	if err := godotenv.Load(); err != nil {
-		fmt.Printf("failed to load .env file: %s\n", err.Error())
+func loadConfig() error {
+	if err := config.Load(&AppConfig, config.WithDotEnv()); err != nil {
+		return err
 	}
-
-	AppConfig.AccessKey = sharedmodule.GetEnv("ACCESS_KEY", "")
-	AppConfig.SecretKey = sharedmodule.GetEnv("SECRET_KEY", "")
-	AppConfig.DnsZoneId = sharedmodule.GetEnv("DNS_ZONE_ID", "")
-	AppConfig.DnsRecordIp = sharedmodule.GetEnv("DNS_RECORD_IP", "")
-	AppConfig.DnsZoneName = sharedmodule.GetEnv("DNS_ZONE_NAME", "onhuawei.cloud")
+	slog.Info("configuration loaded", slog.Any("config", config.Masked(&AppConfig)))
+	return nil
 }
 
 type handler struct {
-	Client *dns.DnsClient
+	mu     sync.Mutex
+	client *dns.DnsClient
+	creds  fgcommon.Credentials
 }
 
-func (h *handler) SmnTrigger(payload []byte, ctx fgcontext.RuntimeContext) (interface{}, error) {
-	var smnEvent smn.SMNTriggerEvent
-	err := json.Unmarshal(payload, &smnEvent)
+// dnsClient returns a DNS client for the credentials of the invocation. It
+// is only rebuilt when they change, e.g. when the agency's temporary keys
+// are rotated.
+func (h *handler) dnsClient(ctx fgcontext.RuntimeContext) (*dns.DnsClient, error) {
+	creds, err := ctx.Credentials().Retrieve()
 	if err != nil {
-		slog.Error("unmarshal payload failed")
-		return "invalid data", err
+		return nil, err
 	}
-	ctx.GetLogger().Logf("payload:%s", smnEvent.String())
 
-	var c int = 1
-	for _, record := range smnEvent.Record {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.client != nil && h.creds == creds {
+		return h.client, nil
+	}
 
-		var hd sharedmodule.HostingDetail
-		if err := json.Unmarshal([]byte(record.Smn.Message), &hd); err != nil {
-			slog.Error("unmarshal message failed")
-			return "invalid data", err
-		}
+	cred, err := basic.NewCredentialsBuilder().
+		WithAk(creds.AK).
+		WithSk(creds.SK).
+		WithSecurityToken(creds.SecurityToken).
+		SafeBuild()
+	if err != nil {
+		return nil, fmt.Errorf("create huawei credential failed: %w", err)
+	}
 
-		slog.Info(fmt.Sprintf("hosting detail #%d:%+v", c, hd))
-		c++
+	dnsHcClient, err := dns.DnsClientBuilder().WithCredential(cred).WithRegion(dnsRegion.CN_NORTH_1).SafeBuild()
+	if err != nil {
+		return nil, fmt.Errorf("create huawei dns client failed: %w", err)
+	}
 
-		var ttl int32 = 60
+	h.client = dns.NewDnsClient(dnsHcClient)
+	h.creds = creds
+	return h.client, nil
+}
 
-		_, err = h.Client.CreateRecordSet(&dnsModel.CreateRecordSetRequest{
-			ZoneId: AppConfig.DnsZoneId,
-			Body: &dnsModel.CreateRecordSetRequestBody{
-				Name:    hd.SubDomain + "." + AppConfig.DnsZoneName,
-				Type:    "A",
-				Ttl:     &ttl,
-				Records: []string{AppConfig.DnsRecordIp},
-			},
-		})
+// SmnRecord creates the DNS record of one hosting detail. A failed record
+// fails the whole event, which SMN then redelivers. runtime.IdempotentRecord
+// skips the records that already succeeded and fails the event again while
+// a record is still being processed by an earlier delivery, e.g. one that
+// timed out. A record set that exists with the same IP counts as created.
+func (h *handler) SmnRecord(_ context.Context, record smn.SMNRecord, ctx fgcontext.RuntimeContext) error {
+	var hd sharedmodule.HostingDetail
+	if err := json.Unmarshal([]byte(record.Smn.Message), &hd); err != nil {
+		return fnhandler.NewUserError(http.StatusBadRequest, fnhandler.ErrorTypeValidation, "invalid smn message: "+err.Error(), nil)
+	}
 
-		if err != nil {
-			slog.Error("create dns record failed", err.Error())
-			return "create dns record failed", err
-		}
+	slog.Info(fmt.Sprintf("hosting detail:%+v", hd))
+
+	client, err := h.dnsClient(ctx)
+	if err != nil {
+		return err
 	}
 
-	slog.Info(fmt.Sprintf("Processed %d records", c-1))
-	slog.Info("===========")
-	return "ok", nil
-}
+	name := hd.SubDomain + "." + AppConfig.DnsZoneName
+	exists, err := recordSetExists(client, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		slog.Info("dns record already exists", slog.String("name", name))
+		return nil
+	}
 
-func main() {
+	var ttl int32 = 60
 
-	loadConfig()
+	_, err = client.CreateRecordSet(&dnsModel.CreateRecordSetRequest{
+		ZoneId: AppConfig.DnsZoneId,
+		Body: &dnsModel.CreateRecordSetRequestBody{
+			Name:    name,
+			Type:    "A",
+			Ttl:     &ttl,
+			Records: []string{AppConfig.DnsRecordIp},
+		},
+	})
 
-	cred, err := basic.NewCredentialsBuilder().WithAk(AppConfig.AccessKey).WithSk(AppConfig.SecretKey).SafeBuild()
 	if err != nil {
-		slog.Error("create huawei credential failed", err.Error())
-		os.Exit(1)
+		return fnhandler.NewUserError(http.StatusBadGateway, fnhandler.ErrorTypeUpstream, "create dns record failed: "+err.Error(), nil)
 	}
+	return nil
+}
 
-	dnsHcClient, err := dns.DnsClientBuilder().WithCredential(cred).WithRegion(dnsRegion.CN_NORTH_1).SafeBuild()
+// recordSetExists reports whether the A record set name already points to
+// DNS_RECORD_IP. A record set pointing elsewhere is a conflict that a retry
+// cannot resolve.
+func recordSetExists(client *dns.DnsClient, name string) (bool, error) {
+	resp, err := client.ListRecordSetsByZone(&dnsModel.ListRecordSetsByZoneRequest{
+		ZoneId: AppConfig.DnsZoneId,
+		Name:   stringPtr(name),
+		Type:   stringPtr("A"),
+	})
 	if err != nil {
-		slog.Error("create huawei dns client failed", err.Error())
-		os.Exit(1)
+		return false, fnhandler.NewUserError(http.StatusBadGateway, fnhandler.ErrorTypeUpstream, "list dns records failed: "+err.Error(), nil)
+	}
+	if resp.Recordsets == nil {
+		return false, nil
+	}
+	for _, recordSet := range *resp.Recordsets {
+		if recordSet.Name == nil || strings.TrimSuffix(*recordSet.Name, ".") != name {
+			continue
+		}
+		if recordSet.Records != nil && slices.Contains(*recordSet.Records, AppConfig.DnsRecordIp) {
+			return true, nil
+		}
+		return false, fnhandler.NewUserError(http.StatusConflict, fnhandler.ErrorTypeValidation, "dns record "+name+" already exists with other records", nil)
 	}
+	return false, nil
+}
+
+// Initialize builds the DNS client once per instance, before the first
+// invocation, so missing credentials fail fast.
+func (h *handler) Initialize(ctx fgcontext.RuntimeContext) error {
+	_, err := h.dnsClient(ctx)
+	return err
+}
 
-	client := dns.NewDnsClient(dnsHcClient)
+func main() {
 
-	h := &handler{
-		Client: client,
+	if err := loadConfig(); err != nil {
+		slog.Error("failed to load configuration", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
 
-	runtime.Register(h.SmnTrigger)
+	h := &handler{}
+	processed := runtime.NewMemoryIdempotencyStore()
+
+	runtime.Use(fnhandler.RequestLogging())
+	runtime.RegisterInitializer(h.Initialize)
+	if err := runtime.RegisterTyped(runtime.SMNBatch(runtime.IdempotentRecord(processed, 24*time.Hour, runtime.SMNMessageKey, h.SmnRecord))); err != nil {
+		slog.Error("function runtime stopped", slog.String("error", err.Error()))
+		os.Exit(runtime.ExitCode(err))
+	}
 }
 
 func stringPtr(s string) *string {
@@ -0,0 +1,175 @@
+package apig
+
+import (
+    "bytes"
+    "context"
+    "encoding/base64"
+    "fmt"
+    "mime"
+    "net/http"
+    "net/url"
+    "strings"
+    "unicode/utf8"
+)
+
+type contextKey int
+
+const (
+    pathParametersKey contextKey = iota
+    requestContextKey
+)
+
+// NewHTTPRequest converts e into the *http.Request a net/http server would
+// build for the same call. The body is base64-decoded when
+// e.IsBase64Encoded is set, and the path parameters and APIG request context
+// are available through PathParameter and RequestContextFromRequest.
+func NewHTTPRequest(ctx context.Context, e *APIGTriggerEvent) (*http.Request, error) {
+    body := []byte(e.Body)
+    if e.IsBase64Encoded {
+        decoded, err := base64.StdEncoding.DecodeString(e.Body)
+        if err != nil {
+            return nil, fmt.Errorf("decode base64 body: %w", err)
+        }
+        body = decoded
+    }
+
+    method := e.HttpMethod
+    if method == "" {
+        method = http.MethodGet
+    }
+    path := e.Path
+    if !strings.HasPrefix(path, "/") {
+        path = "/" + path
+    }
+    target := &url.URL{Path: path}
+    if len(e.QueryStringParameters) > 0 {
+        query := url.Values{}
+        for key, value := range e.QueryStringParameters {
+            query.Set(key, value)
+        }
+        target.RawQuery = query.Encode()
+    }
+
+    ctx = context.WithValue(ctx, pathParametersKey, e.PathParameters)
+    ctx = context.WithValue(ctx, requestContextKey, e.RequestContext)
+    req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    for key, value := range e.Headers {
+        req.Header.Set(key, value)
+    }
+    req.Host = req.Header.Get("Host")
+    req.RemoteAddr = e.RequestContext.SourceIp
+    req.RequestURI = target.RequestURI()
+    return req, nil
+}
+
+// PathParameter returns the APIG path parameter name of a request built by
+// NewHTTPRequest.
+func PathParameter(r *http.Request, name string) string {
+    params, _ := r.Context().Value(pathParametersKey).(map[string]string)
+    return params[name]
+}
+
+// RequestContextFromRequest returns the APIG request context of a request
+// built by NewHTTPRequest.
+func RequestContextFromRequest(r *http.Request) (APIGRequestContext, bool) {
+    rc, ok := r.Context().Value(requestContextKey).(APIGRequestContext)
+    return rc, ok
+}
+
+// ResponseRecorder is an http.ResponseWriter that captures the output of an
+// http.Handler for conversion into an APIGTriggerResponse.
+type ResponseRecorder struct {
+    header      http.Header
+    body        bytes.Buffer
+    statusCode  int
+    wroteHeader bool
+}
+
+func NewResponseRecorder() *ResponseRecorder {
+    return &ResponseRecorder{header: http.Header{}}
+}
+
+func (w *ResponseRecorder) Header() http.Header {
+    return w.header
+}
+
+func (w *ResponseRecorder) WriteHeader(statusCode int) {
+    if w.wroteHeader {
+        return
+    }
+    w.statusCode = statusCode
+    w.wroteHeader = true
+}
+
+func (w *ResponseRecorder) Write(p []byte) (int, error) {
+    if !w.wroteHeader {
+        if w.header.Get("Content-Type") == "" {
+            w.header.Set("Content-Type", http.DetectContentType(p))
+        }
+        w.WriteHeader(http.StatusOK)
+    }
+    return w.body.Write(p)
+}
+
+// Response converts the recorded output. Textual bodies are returned as is,
+// anything else is base64-encoded with IsBase64Encoded set. APIG headers are
+// single valued, so repeated headers are joined with ", ".
+func (w *ResponseRecorder) Response() APIGTriggerResponse {
+    statusCode := w.statusCode
+    if statusCode == 0 {
+        statusCode = http.StatusOK
+    }
+    headers := make(map[string]string, len(w.header))
+    for key, values := range w.header {
+        headers[key] = strings.Join(values, ", ")
+    }
+
+    resp := APIGTriggerResponse{Headers: headers, StatusCode: statusCode}
+    body := w.body.Bytes()
+    if isTextual(w.header.Get("Content-Type"), body) {
+        resp.Body = string(body)
+    } else {
+        resp.Body = base64.StdEncoding.EncodeToString(body)
+        resp.IsBase64Encoded = true
+    }
+    return resp
+}
+
+func isTextual(contentType string, body []byte) bool {
+    if len(body) == 0 {
+        return true
+    }
+    if !utf8.Valid(body) {
+        return false
+    }
+    mediaType, _, err := mime.ParseMediaType(contentType)
+    if err != nil {
+        return contentType == ""
+    }
+    switch {
+    case strings.HasPrefix(mediaType, "text/"),
+        strings.HasSuffix(mediaType, "+json"),
+        strings.HasSuffix(mediaType, "+xml"):
+        return true
+    }
+    switch mediaType {
+    case "application/json", "application/xml", "application/javascript",
+        "application/x-www-form-urlencoded":
+        return true
+    }
+    return false
+}
+
+// ServeHTTP runs handler for e and returns its recorded response.
+func ServeHTTP(ctx context.Context, handler http.Handler, e *APIGTriggerEvent) (APIGTriggerResponse, error) {
+    req, err := NewHTTPRequest(ctx, e)
+    if err != nil {
+        return APIGTriggerResponse{}, err
+    }
+    recorder := NewResponseRecorder()
+    handler.ServeHTTP(recorder, req)
+    return recorder.Response(), nil
+}
@@ -1,14 +1,16 @@
 package cts
 
 import (
+    "bytes"
+    "encoding/json"
     "fmt"
 )
 
 type CTS struct {
     Time string `json:"time"`
     User User `json:"user"`
-    Request map[string]string `json:"request"`
-    Response map[string]string `json:"response"`
+    Request json.RawMessage `json:"request"`
+    Response json.RawMessage `json:"response"`
     Code int `json:"code"`
     ServiceType string `json:"service_type"`
     ResourceType string `json:"resource_type"`
@@ -21,6 +23,42 @@ type CTS struct {
     TraceStatus string `json:"trace_status"`
 }
 
+// DecodeRequest decodes the request body of the traced operation into v.
+func (cts *CTS) DecodeRequest(v interface{}) error {
+    if err := decodeBody(cts.Request, v); err != nil {
+        return fmt.Errorf("decode cts request: %w", err)
+    }
+    return nil
+}
+
+// DecodeResponse decodes the response body of the traced operation into v.
+func (cts *CTS) DecodeResponse(v interface{}) error {
+    if err := decodeBody(cts.Response, v); err != nil {
+        return fmt.Errorf("decode cts response: %w", err)
+    }
+    return nil
+}
+
+// decodeBody decodes body into v. Services record bodies either as JSON
+// values or as strings holding JSON, both are accepted.
+func decodeBody(body json.RawMessage, v interface{}) error {
+    trimmed := bytes.TrimSpace(body)
+    if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+        return nil
+    }
+    if trimmed[0] == '"' {
+        var embedded string
+        if err := json.Unmarshal(trimmed, &embedded); err != nil {
+            return err
+        }
+        if embedded == "" {
+            return nil
+        }
+        trimmed = []byte(embedded)
+    }
+    return json.Unmarshal(trimmed, v)
+}
+
 func (cts *CTS) String() string {
     return fmt.Sprintf(`CTS{
                                   time='%v',
@@ -37,7 +75,7 @@ func (cts *CTS) String() string {
                                   record_time='%v',
                                   trace_id='%v',
                                   trace_status='%v'
-                               }`, cts.Time, cts.User, cts.Request, cts.Response, cts.Code, cts.ServiceType, cts.ResourceType,
+                               }`, cts.Time, cts.User, string(cts.Request), string(cts.Response), cts.Code, cts.ServiceType, cts.ResourceType,
                                    cts.ResourceName,cts.ResourceId, cts.TraceName, cts.TraceType, cts.RecordTime, cts.TraceId,
                                    cts.TraceStatus)
 }
\ No newline at end of file
@@ -8,6 +8,16 @@ type CTSTriggerEvent struct {
     Cts CTS  `json:"cts"`
 }
 
+// Matches reports whether the trace satisfies any of filters.
+func (e *CTSTriggerEvent) Matches(filters ...Filter) bool {
+    for i := range filters {
+        if filters[i].Match(&e.Cts) {
+            return true
+        }
+    }
+    return false
+}
+
 func (e *CTSTriggerEvent) String() string {
     return fmt.Sprintf(`CTSTriggerEvent{
                                   cts=%+v
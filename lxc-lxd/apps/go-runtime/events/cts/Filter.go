@@ -0,0 +1,56 @@
+package cts
+
+import (
+    "path"
+    "strings"
+)
+
+// Filter is a rule matching audit traces. Every non-empty field has to
+// match, a list matching when it contains the trace's value, compared case
+// insensitively. ResourceName is a path.Match pattern. For example, the
+// deletion of a record set in the onhuawei.cloud zone:
+//
+//     cts.Filter{
+//         ServiceTypes:  []string{"DNS"},
+//         ResourceTypes: []string{"recordset"},
+//         TraceNames:    []string{"deleteRecordSet"},
+//         ResourceName:  "*.onhuawei.cloud.",
+//     }
+type Filter struct {
+    ServiceTypes []string
+    ResourceTypes []string
+    TraceNames []string
+    TraceStatuses []string
+    UserNames []string
+    DomainNames []string
+    ResourceName string
+}
+
+// Match reports whether cts satisfies every rule of f.
+func (f *Filter) Match(cts *CTS) bool {
+    if !matchAny(f.ServiceTypes, cts.ServiceType) ||
+        !matchAny(f.ResourceTypes, cts.ResourceType) ||
+        !matchAny(f.TraceNames, cts.TraceName) ||
+        !matchAny(f.TraceStatuses, cts.TraceStatus) ||
+        !matchAny(f.UserNames, cts.User.Name) ||
+        !matchAny(f.DomainNames, cts.User.Domain.Name) {
+        return false
+    }
+    if f.ResourceName != "" {
+        matched, err := path.Match(strings.ToLower(f.ResourceName), strings.ToLower(cts.ResourceName))
+        return err == nil && matched
+    }
+    return true
+}
+
+func matchAny(values []string, value string) bool {
+    if len(values) == 0 {
+        return true
+    }
+    for _, v := range values {
+        if strings.EqualFold(v, value) {
+            return true
+        }
+    }
+    return false
+}
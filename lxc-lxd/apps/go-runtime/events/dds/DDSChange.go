@@ -0,0 +1,69 @@
+package dds
+
+import (
+    "fmt"
+)
+
+// Change stream operation types.
+const (
+    OperationInsert  = "insert"
+    OperationUpdate  = "update"
+    OperationReplace = "replace"
+    OperationDelete  = "delete"
+)
+
+// DDSChange is one change stream event of a DDS collection. The documents
+// are delivered as MongoDB extended JSON, see ExtendedJSON.
+type DDSChange struct {
+    SizeBytes     string       `json:"size_bytes"`
+    Token         ExtendedJSON `json:"token"`
+    OperationType string       `json:"operation_type,omitempty"`
+    NS            ExtendedJSON `json:"ns"`
+    DocumentKey   ExtendedJSON `json:"document_key,omitempty"`
+    FullDocument  ExtendedJSON `json:"full_document"`
+}
+
+// Namespace identifies the collection a change happened in.
+type Namespace struct {
+    DB         string `json:"db"`
+    Collection string `json:"coll"`
+}
+
+func (n Namespace) String() string {
+    return n.DB + "." + n.Collection
+}
+
+func (c *DDSChange) Namespace() (Namespace, error) {
+    var ns Namespace
+    if err := c.NS.Decode(&ns); err != nil {
+        return ns, fmt.Errorf("decode dds namespace: %w", err)
+    }
+    return ns, nil
+}
+
+// DocumentID returns the _id of the changed document, taken from the
+// document key or, when the trigger does not deliver one, the full document.
+// ObjectIds are returned as their hex string.
+func (c *DDSChange) DocumentID() (interface{}, error) {
+    source := c.DocumentKey
+    if len(source) == 0 {
+        source = c.FullDocument
+    }
+    var key struct {
+        ID interface{} `json:"_id"`
+    }
+    if err := source.Decode(&key); err != nil {
+        return nil, fmt.Errorf("decode dds document key: %w", err)
+    }
+    return key.ID, nil
+}
+
+func (c *DDSChange) String() string {
+    return fmt.Sprintf(`DDSChange{
+                                 size_bytes=%v,
+                                 operation_type=%v,
+                                 ns=%s,
+                                 document_key=%s,
+                                 full_document=%s
+                               }`, c.SizeBytes, c.OperationType, c.NS, c.DocumentKey, c.FullDocument)
+}
@@ -9,7 +9,7 @@ type DDSRecord struct {
     EventVersion  string            `json:"event_version"`
     EventSource   string            `json:"event_source"`
     Region        string            `json:"region"`
-    Dds           map[string]string `json:"dds"`
+    Dds           DDSChange         `json:"dds"`
     EventSourceId string            `json:"event_source_id"`
 }
 
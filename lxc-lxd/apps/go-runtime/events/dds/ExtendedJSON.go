@@ -0,0 +1,162 @@
+package dds
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "time"
+)
+
+// ExtendedJSON is a document in MongoDB extended JSON. DDS sends documents
+// as strings holding extended JSON; plain JSON objects are accepted as well.
+// It is written back as a string, like on the wire.
+type ExtendedJSON []byte
+
+func (d *ExtendedJSON) UnmarshalJSON(data []byte) error {
+    trimmed := bytes.TrimSpace(data)
+    switch {
+    case bytes.Equal(trimmed, []byte("null")):
+        *d = nil
+    case len(trimmed) > 0 && trimmed[0] == '"':
+        var s string
+        if err := json.Unmarshal(trimmed, &s); err != nil {
+            return err
+        }
+        *d = ExtendedJSON(s)
+    default:
+        *d = append((*d)[:0], trimmed...)
+    }
+    return nil
+}
+
+func (d ExtendedJSON) MarshalJSON() ([]byte, error) {
+    return json.Marshal(string(d))
+}
+
+func (d ExtendedJSON) String() string {
+    return string(d)
+}
+
+// Decode converts the document to plain JSON and unmarshals it into v:
+// $oid, $uuid and $symbol become strings, $numberInt, $numberLong,
+// $numberDouble and $numberDecimal numbers, $date an RFC 3339 string and
+// $binary its base64 string, so they decode into string, numeric,
+// time.Time and []byte fields. An empty document leaves v unchanged.
+func (d ExtendedJSON) Decode(v interface{}) error {
+    if len(bytes.TrimSpace(d)) == 0 {
+        return nil
+    }
+    decoder := json.NewDecoder(bytes.NewReader(d))
+    decoder.UseNumber()
+    var document interface{}
+    if err := decoder.Decode(&document); err != nil {
+        return fmt.Errorf("invalid extended json: %w", err)
+    }
+    plain, err := toPlainJSON(document)
+    if err != nil {
+        return err
+    }
+    data, err := json.Marshal(plain)
+    if err != nil {
+        return err
+    }
+    return json.Unmarshal(data, v)
+}
+
+// DecodeDocument decodes d into a T, see ExtendedJSON.Decode.
+func DecodeDocument[T any](d ExtendedJSON) (T, error) {
+    var v T
+    err := d.Decode(&v)
+    return v, err
+}
+
+func toPlainJSON(value interface{}) (interface{}, error) {
+    switch value := value.(type) {
+    case []interface{}:
+        for i, item := range value {
+            plain, err := toPlainJSON(item)
+            if err != nil {
+                return nil, err
+            }
+            value[i] = plain
+        }
+        return value, nil
+    case map[string]interface{}:
+        if plain, ok, err := convertWrapper(value); ok || err != nil {
+            return plain, err
+        }
+        for key, item := range value {
+            plain, err := toPlainJSON(item)
+            if err != nil {
+                return nil, err
+            }
+            value[key] = plain
+        }
+        return value, nil
+    }
+    return value, nil
+}
+
+// convertWrapper converts a single extended JSON type wrapper such as
+// {"$oid": "..."}. ok is false for ordinary objects.
+func convertWrapper(value map[string]interface{}) (interface{}, bool, error) {
+    if binary, ok := value["$binary"]; ok {
+        return convertBinary(binary), true, nil
+    }
+    if len(value) != 1 {
+        return nil, false, nil
+    }
+    for key, wrapped := range value {
+        switch key {
+        case "$oid", "$uuid", "$symbol":
+            return wrapped, true, nil
+        case "$numberInt", "$numberLong", "$numberDouble", "$numberDecimal":
+            s, ok := wrapped.(string)
+            if !ok {
+                return wrapped, true, nil
+            }
+            if _, err := strconv.ParseFloat(s, 64); err != nil {
+                // Infinity and NaN have no JSON number form.
+                return s, true, nil
+            }
+            return json.Number(s), true, nil
+        case "$date":
+            date, err := convertDate(wrapped)
+            return date, true, err
+        }
+    }
+    return nil, false, nil
+}
+
+// convertBinary handles both the canonical {"$binary": {"base64": ...}} and
+// the legacy {"$binary": "...", "$type": "00"} forms.
+func convertBinary(binary interface{}) interface{} {
+    if canonical, ok := binary.(map[string]interface{}); ok {
+        return canonical["base64"]
+    }
+    return binary
+}
+
+func convertDate(date interface{}) (interface{}, error) {
+    var millis string
+    switch date := date.(type) {
+    case string:
+        return date, nil
+    case json.Number:
+        millis = date.String()
+    case map[string]interface{}:
+        s, ok := date["$numberLong"].(string)
+        if !ok {
+            return nil, fmt.Errorf("invalid extended json date %v", date)
+        }
+        millis = s
+    default:
+        return nil, fmt.Errorf("invalid extended json date %v", date)
+    }
+    ms, err := strconv.ParseInt(millis, 10, 64)
+    if err != nil {
+        return nil, fmt.Errorf("invalid extended json date %v: %w", date, err)
+    }
+    return time.UnixMilli(ms).UTC().Format(time.RFC3339Nano), nil
+}
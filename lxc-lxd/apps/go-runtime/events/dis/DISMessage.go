@@ -5,15 +5,15 @@ import (
 )
 
 type DISMessage struct {
-    NextPatitionCursor string `json:"next_patition_cursor"`
+    NextPartitionCursor string `json:"next_partition_cursor"`
     Records []DISRecord `json:"records"`
-    MillisBehindLatest string `json:"millisBehindLatest"`
+    MillisBehindLatest MillisBehind `json:"millisBehindLatest"`
 }
 
 func (d *DISMessage) String() string {
     return fmt.Sprintf(`DISMessage{
-                                 next_patition_cursor=%v,
+                                 next_partition_cursor=%v,
                                  records=%+v,
                                  millisBehindLatest=%v
-                               }`, d.NextPatitionCursor, d.Records, d.MillisBehindLatest)
-}
\ No newline at end of file
+                               }`, d.NextPartitionCursor, d.Records, d.MillisBehindLatest)
+}
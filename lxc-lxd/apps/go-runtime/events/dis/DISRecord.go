@@ -1,6 +1,7 @@
 package dis
 
 import (
+    "encoding/base64"
     "fmt"
 )
 
@@ -10,10 +11,19 @@ type DISRecord struct {
     SequenceNumber string `json:"sequence_number"`
 }
 
+// DecodedData returns the record payload, which DIS delivers base64-encoded.
+func (r *DISRecord) DecodedData() ([]byte, error) {
+    data, err := base64.StdEncoding.DecodeString(r.Data)
+    if err != nil {
+        return nil, fmt.Errorf("decode base64 data: %w", err)
+    }
+    return data, nil
+}
+
 func (r *DISRecord) String() string {
     return fmt.Sprintf(`DISRecord{
                                  partition_key=%v,
                                  data=%v,
                                  sequence_number=%v
                                }`, r.PartitionKey, r.Data, r.SequenceNumber)
-}
\ No newline at end of file
+}
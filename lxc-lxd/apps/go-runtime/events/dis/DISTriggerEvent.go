@@ -5,10 +5,32 @@ import (
 )
 
 type DISTriggerEvent struct {
-    ShardID string
-    Message DISMessage
-    Tag string
-    StreamName string
+    ShardID string `json:"ShardID"`
+    Message DISMessage `json:"Message"`
+    Tag string `json:"Tag"`
+    StreamName string `json:"StreamName"`
+}
+
+// ForEachRecord calls fn with every record of the event and its decoded
+// data. A record whose data cannot be decoded or for which fn returns an
+// error does not stop the iteration; all such failures are returned together
+// as RecordErrors so the caller can report which records to retry.
+func (e *DISTriggerEvent) ForEachRecord(fn func(index int, record *DISRecord, data []byte) error) error {
+    var errs RecordErrors
+    for i := range e.Message.Records {
+        record := &e.Message.Records[i]
+        data, err := record.DecodedData()
+        if err == nil {
+            err = fn(i, record, data)
+        }
+        if err != nil {
+            errs = append(errs, &RecordError{Index: i, SequenceNumber: record.SequenceNumber, Err: err})
+        }
+    }
+    if len(errs) == 0 {
+        return nil
+    }
+    return errs
 }
 
 func (e *DISTriggerEvent) String() string {
@@ -18,4 +40,4 @@ func (e *DISTriggerEvent) String() string {
                                   Tag=%v,
                                   StreamName=%v
                                }`, e.ShardID, e.Message, e.Tag, e.StreamName)
-}
\ No newline at end of file
+}
@@ -0,0 +1,49 @@
+package dis
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "time"
+)
+
+// MillisBehind is how many milliseconds the delivered records lag behind the
+// tip of the stream. DIS sends it as a string, empty when the consumer is
+// caught up; plain JSON numbers are accepted as well.
+type MillisBehind int64
+
+func (m MillisBehind) Duration() time.Duration {
+    return time.Duration(m) * time.Millisecond
+}
+
+func (m *MillisBehind) UnmarshalJSON(data []byte) error {
+    if bytes.Equal(data, []byte("null")) {
+        *m = 0
+        return nil
+    }
+    text := string(data)
+    if len(data) > 0 && data[0] == '"' {
+        if err := json.Unmarshal(data, &text); err != nil {
+            return err
+        }
+        if text == "" {
+            *m = 0
+            return nil
+        }
+    }
+    value, err := strconv.ParseInt(text, 10, 64)
+    if err != nil {
+        return fmt.Errorf("invalid millisBehindLatest %s", data)
+    }
+    *m = MillisBehind(value)
+    return nil
+}
+
+// MarshalJSON writes the wire format back, zero as the empty string.
+func (m MillisBehind) MarshalJSON() ([]byte, error) {
+    if m == 0 {
+        return []byte(`""`), nil
+    }
+    return json.Marshal(strconv.FormatInt(int64(m), 10))
+}
@@ -0,0 +1,41 @@
+package dis
+
+import (
+    "fmt"
+    "strings"
+)
+
+// RecordError is the failure of a single record, see
+// DISTriggerEvent.ForEachRecord.
+type RecordError struct {
+    Index int
+    SequenceNumber string
+    Err error
+}
+
+func (e *RecordError) Error() string {
+    return fmt.Sprintf("record %d (sequence number %s): %s", e.Index, e.SequenceNumber, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+    return e.Err
+}
+
+type RecordErrors []*RecordError
+
+func (e RecordErrors) Error() string {
+    messages := make([]string, 0, len(e))
+    for _, err := range e {
+        messages = append(messages, err.Error())
+    }
+    return fmt.Sprintf("%d of the records failed: %s", len(e), strings.Join(messages, "; "))
+}
+
+// SequenceNumbers returns the sequence numbers of the failed records.
+func (e RecordErrors) SequenceNumbers() []string {
+    numbers := make([]string, 0, len(e))
+    for _, err := range e {
+        numbers = append(numbers, err.SequenceNumber)
+    }
+    return numbers
+}
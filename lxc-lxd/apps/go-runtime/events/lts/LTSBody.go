@@ -1,20 +1,46 @@
 package lts
 
 import (
+    "bytes"
+    "compress/gzip"
     "encoding/base64"
     "fmt"
+    "io/ioutil"
 )
 
 type LTSBody struct {
     Data string `json:"data"`
 }
 
-func (b *LTSBody) GetRawData() string {
+// GetRawData returns the document carried in data, base64-decoded and
+// gunzipped when LTS compressed it.
+func (b *LTSBody) GetRawData() ([]byte, error) {
     res, err := base64.StdEncoding.DecodeString(b.Data)
     if err != nil {
-        return ""
+        return nil, fmt.Errorf("decode base64 data: %w", err)
     }
-    return string(res)
+    if !bytes.HasPrefix(res, []byte{0x1f, 0x8b}) {
+        return res, nil
+    }
+    reader, err := gzip.NewReader(bytes.NewReader(res))
+    if err != nil {
+        return nil, fmt.Errorf("decompress data: %w", err)
+    }
+    defer reader.Close()
+    res, err = ioutil.ReadAll(reader)
+    if err != nil {
+        return nil, fmt.Errorf("decompress data: %w", err)
+    }
+    return res, nil
+}
+
+// DecodeLogs parses the document carried in data into a LTSLogBatch.
+func (b *LTSBody) DecodeLogs() (*LTSLogBatch, error) {
+    data, err := b.GetRawData()
+    if err != nil {
+        return nil, err
+    }
+    return ParseLogBatch(data)
 }
 
 func (b *LTSBody) String() string {
@@ -0,0 +1,116 @@
+package lts
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "time"
+)
+
+// LTSLogBatch is the set of log entries an LTS subscription delivers for one
+// log stream.
+type LTSLogBatch struct {
+    Owner string
+    LogGroupID string
+    LogStreamID string
+    Entries []LTSLogEntry
+}
+
+// LTSLogEntry is a single collected log line. Labels holds the remaining
+// string attributes of the entry, such as host_name, ip and path.
+type LTSLogEntry struct {
+    Message string
+    Time time.Time
+    LineNo int64
+    LogUID string
+    Labels map[string]string
+}
+
+// ltsDocument is the wire format of the decoded data. logs is usually a
+// JSON string holding the entries array, but a plain array is accepted too.
+type ltsDocument struct {
+    Logs json.RawMessage `json:"logs"`
+    Owner string `json:"owner"`
+    LogGroupID string `json:"log_group_id"`
+    LogTopicID string `json:"log_topic_id"`
+}
+
+// ParseLogBatch parses a decoded LTS document, see LTSBody.GetRawData.
+func ParseLogBatch(data []byte) (*LTSLogBatch, error) {
+    var document ltsDocument
+    if err := json.Unmarshal(data, &document); err != nil {
+        return nil, fmt.Errorf("invalid lts document: %w", err)
+    }
+
+    logs := []byte(document.Logs)
+    if bytes.HasPrefix(bytes.TrimSpace(logs), []byte(`"`)) {
+        var embedded string
+        if err := json.Unmarshal(logs, &embedded); err != nil {
+            return nil, fmt.Errorf("invalid lts logs: %w", err)
+        }
+        logs = []byte(embedded)
+    }
+
+    var rawEntries []map[string]json.RawMessage
+    if len(bytes.TrimSpace(logs)) > 0 {
+        if err := json.Unmarshal(logs, &rawEntries); err != nil {
+            return nil, fmt.Errorf("invalid lts logs: %w", err)
+        }
+    }
+
+    batch := &LTSLogBatch{
+        Owner: document.Owner,
+        LogGroupID: document.LogGroupID,
+        LogStreamID: document.LogTopicID,
+        Entries: make([]LTSLogEntry, 0, len(rawEntries)),
+    }
+    for i, raw := range rawEntries {
+        entry, err := parseLogEntry(raw)
+        if err != nil {
+            return nil, fmt.Errorf("invalid lts log entry %d: %w", i, err)
+        }
+        batch.Entries = append(batch.Entries, entry)
+    }
+    return batch, nil
+}
+
+func parseLogEntry(raw map[string]json.RawMessage) (LTSLogEntry, error) {
+    entry := LTSLogEntry{Labels: map[string]string{}}
+    for key, value := range raw {
+        var err error
+        switch key {
+        case "message":
+            err = json.Unmarshal(value, &entry.Message)
+        case "log_uid":
+            err = json.Unmarshal(value, &entry.LogUID)
+        case "time":
+            var millis int64
+            millis, err = parseInt(value)
+            entry.Time = time.UnixMilli(millis)
+        case "line_no":
+            entry.LineNo, err = parseInt(value)
+        default:
+            var label string
+            if json.Unmarshal(value, &label) == nil {
+                entry.Labels[key] = label
+            } else {
+                entry.Labels[key] = string(value)
+            }
+        }
+        if err != nil {
+            return entry, fmt.Errorf("field %s: %w", key, err)
+        }
+    }
+    return entry, nil
+}
+
+// parseInt accepts a JSON number or a string holding one.
+func parseInt(value json.RawMessage) (int64, error) {
+    text := string(value)
+    var s string
+    if json.Unmarshal(value, &s) == nil {
+        text = s
+    }
+    return strconv.ParseInt(text, 10, 64)
+}
@@ -8,8 +8,13 @@ type LTSTriggerEvent struct {
     Lts LTSBody `json:"lts"`
 }
 
+// DecodeLogs decodes the log entries carried by the event.
+func (e *LTSTriggerEvent) DecodeLogs() (*LTSLogBatch, error) {
+    return e.Lts.DecodeLogs()
+}
+
 func (e *LTSTriggerEvent) String() string {
     return fmt.Sprintf(`LTSTriggerEvent{
                                  lts=%+v
                                }`, e.Lts)
-}
\ No newline at end of file
+}
@@ -0,0 +1,62 @@
+package testevents
+
+import (
+    "encoding/base64"
+    "strings"
+
+    "huaweicloud.com/go-runtime/events/apig"
+)
+
+type APIGEventBuilder struct {
+    event apig.APIGTriggerEvent
+}
+
+// NewAPIGEvent starts from the apig fixture with method and path replaced
+// and no query string.
+func NewAPIGEvent(method, path string) *APIGEventBuilder {
+    b := &APIGEventBuilder{}
+    mustDecodeFixture(FixtureAPIG, &b.event)
+    b.event.HttpMethod = method
+    b.event.Path = path
+    b.event.QueryStringParameters = map[string]string{}
+    return b
+}
+
+// WithBody sets the body base64-encoded, as APIG delivers it.
+func (b *APIGEventBuilder) WithBody(body []byte) *APIGEventBuilder {
+    b.event.Body = base64.StdEncoding.EncodeToString(body)
+    b.event.IsBase64Encoded = true
+    return b
+}
+
+// WithJSONBody sets the JSON encoding of body and its content type.
+func (b *APIGEventBuilder) WithJSONBody(body interface{}) *APIGEventBuilder {
+    return b.WithHeader("Content-Type", "application/json").WithBody(mustMarshal(body))
+}
+
+// WithHeader sets a header, lower-casing its name like APIG does.
+func (b *APIGEventBuilder) WithHeader(key, value string) *APIGEventBuilder {
+    b.event.Headers[strings.ToLower(key)] = value
+    return b
+}
+
+func (b *APIGEventBuilder) WithQuery(key, value string) *APIGEventBuilder {
+    b.event.QueryStringParameters[key] = value
+    return b
+}
+
+func (b *APIGEventBuilder) WithPathParameter(key, value string) *APIGEventBuilder {
+    if b.event.PathParameters == nil {
+        b.event.PathParameters = map[string]string{}
+    }
+    b.event.PathParameters[key] = value
+    return b
+}
+
+func (b *APIGEventBuilder) Build() apig.APIGTriggerEvent {
+    return b.event
+}
+
+func (b *APIGEventBuilder) JSON() []byte {
+    return mustMarshal(b.event)
+}
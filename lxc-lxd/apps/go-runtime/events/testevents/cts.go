@@ -0,0 +1,74 @@
+package testevents
+
+import (
+    "strconv"
+    "time"
+
+    "huaweicloud.com/go-runtime/events/cts"
+)
+
+type CTSEventBuilder struct {
+    event cts.CTSTriggerEvent
+}
+
+// NewCTSEvent starts from the cts fixture, a successful FunctionGraph
+// console action.
+func NewCTSEvent() *CTSEventBuilder {
+    b := &CTSEventBuilder{}
+    mustDecodeFixture(FixtureCTS, &b.event)
+    return b
+}
+
+func (b *CTSEventBuilder) WithService(serviceType, resourceType string) *CTSEventBuilder {
+    b.event.Cts.ServiceType = serviceType
+    b.event.Cts.ResourceType = resourceType
+    return b
+}
+
+func (b *CTSEventBuilder) WithResource(resourceName, resourceID string) *CTSEventBuilder {
+    b.event.Cts.ResourceName = resourceName
+    b.event.Cts.ResourceId = resourceID
+    return b
+}
+
+func (b *CTSEventBuilder) WithTrace(traceName, traceStatus string) *CTSEventBuilder {
+    b.event.Cts.TraceName = traceName
+    b.event.Cts.TraceStatus = traceStatus
+    return b
+}
+
+func (b *CTSEventBuilder) WithUser(userName, domainName string) *CTSEventBuilder {
+    b.event.Cts.User.Name = userName
+    b.event.Cts.User.Domain.Name = domainName
+    return b
+}
+
+func (b *CTSEventBuilder) WithCode(code int) *CTSEventBuilder {
+    b.event.Cts.Code = code
+    return b
+}
+
+func (b *CTSEventBuilder) WithTime(t time.Time) *CTSEventBuilder {
+    b.event.Cts.Time = strconv.FormatInt(t.UnixMilli(), 10)
+    return b
+}
+
+// WithRequest sets the JSON encoding of the traced request body.
+func (b *CTSEventBuilder) WithRequest(request interface{}) *CTSEventBuilder {
+    b.event.Cts.Request = mustMarshal(request)
+    return b
+}
+
+// WithResponse sets the JSON encoding of the traced response body.
+func (b *CTSEventBuilder) WithResponse(response interface{}) *CTSEventBuilder {
+    b.event.Cts.Response = mustMarshal(response)
+    return b
+}
+
+func (b *CTSEventBuilder) Build() cts.CTSTriggerEvent {
+    return b.event
+}
+
+func (b *CTSEventBuilder) JSON() []byte {
+    return mustMarshal(b.event)
+}
@@ -0,0 +1,65 @@
+package testevents
+
+import (
+    "huaweicloud.com/go-runtime/events/dds"
+)
+
+type DDSEventBuilder struct {
+    event dds.DDSTriggerEvent
+}
+
+// NewDDSEvent starts from the dds fixture, one change of a small document.
+func NewDDSEvent() *DDSEventBuilder {
+    b := &DDSEventBuilder{}
+    mustDecodeFixture(FixtureDDS, &b.event)
+    return b
+}
+
+func (b *DDSEventBuilder) record() *dds.DDSRecord {
+    return &b.event.Records[len(b.event.Records)-1]
+}
+
+// WithNamespace sets the database and collection of the last record.
+func (b *DDSEventBuilder) WithNamespace(db, collection string) *DDSEventBuilder {
+    b.record().Dds.NS = mustMarshal(dds.Namespace{DB: db, Collection: collection})
+    return b
+}
+
+// WithDocument sets the full document of the last record, JSON encoding
+// anything that is not already a string.
+func (b *DDSEventBuilder) WithDocument(document interface{}) *DDSEventBuilder {
+    b.record().Dds.FullDocument = extendedJSON(document)
+    return b
+}
+
+// WithOperation sets the operation type and document key of the last
+// record, JSON encoding a documentKey that is not already a string.
+func (b *DDSEventBuilder) WithOperation(operationType string, documentKey interface{}) *DDSEventBuilder {
+    b.record().Dds.OperationType = operationType
+    b.record().Dds.DocumentKey = extendedJSON(documentKey)
+    return b
+}
+
+func extendedJSON(document interface{}) dds.ExtendedJSON {
+    if s, ok := document.(string); ok {
+        return dds.ExtendedJSON(s)
+    }
+    return mustMarshal(document)
+}
+
+// AddRecord appends a fresh fixture record, the following With* calls apply
+// to it.
+func (b *DDSEventBuilder) AddRecord() *DDSEventBuilder {
+    var fixture dds.DDSTriggerEvent
+    mustDecodeFixture(FixtureDDS, &fixture)
+    b.event.Records = append(b.event.Records, fixture.Records[0])
+    return b
+}
+
+func (b *DDSEventBuilder) Build() dds.DDSTriggerEvent {
+    return b.event
+}
+
+func (b *DDSEventBuilder) JSON() []byte {
+    return mustMarshal(b.event)
+}
@@ -0,0 +1,58 @@
+package testevents
+
+import (
+    "encoding/base64"
+    "strconv"
+
+    "huaweicloud.com/go-runtime/events/dis"
+)
+
+type DISEventBuilder struct {
+    event dis.DISTriggerEvent
+}
+
+// NewDISEvent starts from the dis fixture, two records on one shard.
+func NewDISEvent() *DISEventBuilder {
+    b := &DISEventBuilder{}
+    mustDecodeFixture(FixtureDIS, &b.event)
+    return b
+}
+
+func (b *DISEventBuilder) WithStreamName(streamName string) *DISEventBuilder {
+    b.event.StreamName = streamName
+    return b
+}
+
+func (b *DISEventBuilder) WithShardID(shardID string) *DISEventBuilder {
+    b.event.ShardID = shardID
+    return b
+}
+
+// WithRecords replaces all records with one record per data item, numbered
+// from 0 like a fresh shard.
+func (b *DISEventBuilder) WithRecords(partitionKey string, data ...[]byte) *DISEventBuilder {
+    b.event.Message.Records = nil
+    for _, item := range data {
+        b.AddRecord(partitionKey, item)
+    }
+    return b
+}
+
+// AddRecord appends a record holding base64-encoded data with the next
+// sequence number.
+func (b *DISEventBuilder) AddRecord(partitionKey string, data []byte) *DISEventBuilder {
+    b.event.Message.Records = append(b.event.Message.Records, dis.DISRecord{
+        PartitionKey:   partitionKey,
+        Data:           base64.StdEncoding.EncodeToString(data),
+        SequenceNumber: strconv.Itoa(len(b.event.Message.Records)),
+    })
+    return b
+}
+
+func (b *DISEventBuilder) Build() dis.DISTriggerEvent {
+    return b.event
+}
+
+func (b *DISEventBuilder) JSON() []byte {
+    return mustMarshal(b.event)
+}
@@ -0,0 +1,201 @@
+// Package testevents builds realistic trigger event payloads for tests and
+// local invocations.
+//
+// Every builder starts from a fixture in fixtures/ that mirrors the payload
+// FunctionGraph sends for that trigger, so only the fields a test cares about
+// need to be set:
+//
+//     payload := testevents.NewSMNEvent().WithMessage(hostingDetail).JSON()
+//
+// Verify checks that the events structs still decode and re-encode every
+// fixture field, i.e. that their JSON tags match the platform shapes.
+package testevents
+
+import (
+    "bytes"
+    "embed"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "sort"
+
+    "huaweicloud.com/go-runtime/events/apig"
+    "huaweicloud.com/go-runtime/events/cts"
+    "huaweicloud.com/go-runtime/events/dds"
+    "huaweicloud.com/go-runtime/events/dis"
+    "huaweicloud.com/go-runtime/events/kafka"
+    "huaweicloud.com/go-runtime/events/lts"
+    "huaweicloud.com/go-runtime/events/smn"
+    "huaweicloud.com/go-runtime/events/timer"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+const (
+    FixtureAPIG  = "apig"
+    FixtureSMN   = "smn"
+    FixtureTimer = "timer"
+    FixtureKafka = "kafka"
+    FixtureDIS   = "dis"
+    FixtureLTS   = "lts"
+    FixtureCTS   = "cts"
+    FixtureDDS   = "dds"
+)
+
+// fixtureSpec describes how a fixture maps onto its events struct. ignored
+// lists the top-level fields the platform sends that the struct does not
+// model on purpose.
+type fixtureSpec struct {
+    name    string
+    target  func() interface{}
+    ignored []string
+}
+
+var fixtureSpecs = []fixtureSpec{
+    {name: FixtureAPIG, target: func() interface{} { return &apig.APIGTriggerEvent{} }},
+    {name: FixtureSMN, target: func() interface{} { return &smn.SMNTriggerEvent{} }, ignored: []string{"functionname", "requestId", "timestamp"}},
+    {name: FixtureTimer, target: func() interface{} { return &timer.TimerTriggerEvent{} }},
+    {name: FixtureKafka, target: func() interface{} { return &kafka.KAFKATriggerEvent{} }},
+    {name: FixtureDIS, target: func() interface{} { return &dis.DISTriggerEvent{} }},
+    {name: FixtureLTS, target: func() interface{} { return &lts.LTSTriggerEvent{} }},
+    {name: FixtureCTS, target: func() interface{} { return &cts.CTSTriggerEvent{} }},
+    {name: FixtureDDS, target: func() interface{} { return &dds.DDSTriggerEvent{} }},
+}
+
+// Fixture returns the raw platform payload of the named fixture, one of the
+// Fixture* constants.
+func Fixture(name string) ([]byte, error) {
+    return fixtures.ReadFile("fixtures/" + name + ".json")
+}
+
+func mustDecodeFixture(name string, v interface{}) {
+    data, err := Fixture(name)
+    if err != nil {
+        panic(err)
+    }
+    if err := json.Unmarshal(data, v); err != nil {
+        panic(fmt.Sprintf("testevents: decode fixture %s: %s", name, err))
+    }
+}
+
+func mustMarshal(v interface{}) []byte {
+    data, err := json.Marshal(v)
+    if err != nil {
+        panic(fmt.Sprintf("testevents: marshal %T: %s", v, err))
+    }
+    return data
+}
+
+// Verify round-trips every fixture through its events struct and reports each
+// field that is lost or changed on the way, which is what a wrong or missing
+// JSON tag looks like. Fields the struct emits but the fixture lacks are only
+// accepted when they hold their zero value.
+func Verify() error {
+    var errs []error
+    for _, spec := range fixtureSpecs {
+        if err := verifyFixture(spec); err != nil {
+            errs = append(errs, fmt.Errorf("%s: %w", spec.name, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+func verifyFixture(spec fixtureSpec) error {
+    data, err := Fixture(spec.name)
+    if err != nil {
+        return err
+    }
+    target := spec.target()
+    if err := json.Unmarshal(data, target); err != nil {
+        return err
+    }
+
+    var want, got interface{}
+    if err := decodeGeneric(data, &want); err != nil {
+        return err
+    }
+    if err := decodeGeneric(mustMarshal(target), &got); err != nil {
+        return err
+    }
+    if fields, ok := want.(map[string]interface{}); ok {
+        for _, key := range spec.ignored {
+            delete(fields, key)
+        }
+    }
+
+    var mismatches []error
+    compareJSON("$", want, got, &mismatches)
+    return errors.Join(mismatches...)
+}
+
+func decodeGeneric(data []byte, v *interface{}) error {
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.UseNumber()
+    return decoder.Decode(v)
+}
+
+func compareJSON(path string, want, got interface{}, mismatches *[]error) {
+    switch want := want.(type) {
+    case map[string]interface{}:
+        gotFields, ok := got.(map[string]interface{})
+        if !ok {
+            *mismatches = append(*mismatches, fmt.Errorf("%s: want object, got %v", path, got))
+            return
+        }
+        for _, key := range sortedKeys(want) {
+            value, ok := gotFields[key]
+            if !ok {
+                *mismatches = append(*mismatches, fmt.Errorf("%s.%s: not mapped by the struct", path, key))
+                continue
+            }
+            compareJSON(path+"."+key, want[key], value, mismatches)
+        }
+        for _, key := range sortedKeys(gotFields) {
+            if _, ok := want[key]; !ok && !isZeroJSON(gotFields[key]) {
+                *mismatches = append(*mismatches, fmt.Errorf("%s.%s: not in the fixture", path, key))
+            }
+        }
+    case []interface{}:
+        gotItems, ok := got.([]interface{})
+        if !ok || len(gotItems) != len(want) {
+            *mismatches = append(*mismatches, fmt.Errorf("%s: want %d items, got %v", path, len(want), got))
+            return
+        }
+        for i := range want {
+            compareJSON(fmt.Sprintf("%s[%d]", path, i), want[i], gotItems[i], mismatches)
+        }
+    default:
+        if !reflect.DeepEqual(want, got) {
+            *mismatches = append(*mismatches, fmt.Errorf("%s: want %v, got %v", path, want, got))
+        }
+    }
+}
+
+func isZeroJSON(v interface{}) bool {
+    switch v := v.(type) {
+    case nil:
+        return true
+    case string:
+        return v == ""
+    case bool:
+        return !v
+    case json.Number:
+        return v.String() == "0"
+    case map[string]interface{}:
+        return len(v) == 0
+    case []interface{}:
+        return len(v) == 0
+    }
+    return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
@@ -0,0 +1,243 @@
+package testevents
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "reflect"
+    "testing"
+    "time"
+
+    "huaweicloud.com/go-runtime/events/apig"
+    "huaweicloud.com/go-runtime/events/cts"
+    "huaweicloud.com/go-runtime/events/dds"
+    "huaweicloud.com/go-runtime/events/dis"
+    "huaweicloud.com/go-runtime/events/kafka"
+    "huaweicloud.com/go-runtime/events/lts"
+    "huaweicloud.com/go-runtime/events/smn"
+    "huaweicloud.com/go-runtime/events/timer"
+)
+
+func TestVerify(t *testing.T) {
+    if err := Verify(); err != nil {
+        t.Fatal(err)
+    }
+}
+
+// roundTrip decodes data into a new value of the type of want, checks it
+// equals want and that encoding it again yields data.
+func roundTrip(t *testing.T, data []byte, want interface{}) {
+    t.Helper()
+    got := reflect.New(reflect.TypeOf(want))
+    if err := json.Unmarshal(data, got.Interface()); err != nil {
+        t.Fatalf("decode %s: %s", data, err)
+    }
+    encoded, err := json.Marshal(got.Interface())
+    if err != nil {
+        t.Fatal(err)
+    }
+    wantEncoded, _ := json.Marshal(want)
+    if !bytes.Equal(encoded, wantEncoded) {
+        t.Fatalf("round trip changed the event:\n got %s\nwant %s", encoded, wantEncoded)
+    }
+    var a, b interface{}
+    json.Unmarshal(data, &a)
+    json.Unmarshal(encoded, &b)
+    if !reflect.DeepEqual(a, b) {
+        t.Fatalf("re-encoded event differs from JSON():\n got %s\nwant %s", encoded, data)
+    }
+}
+
+func TestSMNBuilder(t *testing.T) {
+    b := NewSMNEvent().
+        WithMessage(map[string]string{"subdomain": "demo"}).
+        WithSubject("subject").
+        WithMessageID("m1").
+        WithMessageAttribute("traceparent", "00-1-2-01").
+        AddRecord().
+        WithMessageID("m2")
+    event := b.Build()
+    roundTrip(t, b.JSON(), event)
+
+    if len(event.Record) != 2 {
+        t.Fatalf("records = %d, want 2", len(event.Record))
+    }
+    first := event.Record[0].Smn
+    if first.Message != `{"subdomain":"demo"}` || first.Subject != "subject" || first.MessageId != "m1" {
+        t.Errorf("first record = %+v", first)
+    }
+    if first.MessageAttributes["traceparent"] != "00-1-2-01" {
+        t.Errorf("message attributes = %v", first.MessageAttributes)
+    }
+    if event.Record[1].Smn.MessageId != "m2" {
+        t.Errorf("second message id = %q", event.Record[1].Smn.MessageId)
+    }
+    var _ smn.SMNTriggerEvent = event
+}
+
+func TestKafkaBuilder(t *testing.T) {
+    b := NewKafkaEvent().
+        WithInstanceID("instance").
+        WithRegion("ap-southeast-4").
+        WithMessages("topic-a", "m1", "m2").
+        AddRecord("topic-b", "m3")
+    event := b.Build()
+    roundTrip(t, b.JSON(), event)
+
+    if event.InstanceId != "instance" || event.Region != "ap-southeast-4" {
+        t.Errorf("event = %+v", event)
+    }
+    if len(event.Records) != 2 || len(event.Records[0].Messages) != 2 || event.Records[1].TopicId != "topic-b" {
+        t.Errorf("records = %+v", event.Records)
+    }
+    var _ kafka.KAFKATriggerEvent = event
+}
+
+func TestDISBuilder(t *testing.T) {
+    b := NewDISEvent().
+        WithStreamName("stream").
+        WithShardID("shard-1").
+        WithRecords("pk", []byte("one"), []byte("two"))
+    event := b.Build()
+    roundTrip(t, b.JSON(), event)
+
+    var data []string
+    err := event.ForEachRecord(func(_ int, _ *dis.DISRecord, d []byte) error {
+        data = append(data, string(d))
+        return nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(data, []string{"one", "two"}) {
+        t.Errorf("data = %q", data)
+    }
+    if event.StreamName != "stream" || event.ShardID != "shard-1" {
+        t.Errorf("event = %+v", event)
+    }
+}
+
+func TestLTSBuilder(t *testing.T) {
+    logTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    for _, gzipped := range []bool{false, true} {
+        b := NewLTSEvent().WithLogs("group", "stream", logTime, "line 1", "line 2")
+        if gzipped {
+            b = b.Gzipped()
+        }
+        event := b.Build()
+        roundTrip(t, b.JSON(), event)
+
+        batch, err := event.DecodeLogs()
+        if err != nil {
+            t.Fatalf("gzipped=%v: %s", gzipped, err)
+        }
+        if batch.LogGroupID != "group" || batch.LogStreamID != "stream" || len(batch.Entries) != 2 {
+            t.Fatalf("gzipped=%v: batch = %+v", gzipped, batch)
+        }
+        if batch.Entries[1].Message != "line 2" || !batch.Entries[0].Time.Equal(logTime) {
+            t.Errorf("gzipped=%v: entries = %+v", gzipped, batch.Entries)
+        }
+    }
+    var _ lts.LTSTriggerEvent = NewLTSEvent().Build()
+}
+
+func TestCTSBuilder(t *testing.T) {
+    b := NewCTSEvent().
+        WithService("ECS", "ecs").
+        WithResource("web-1", "id-1").
+        WithTrace("createServer", "normal").
+        WithUser("alice", "domain").
+        WithCode(200).
+        WithRequest(map[string]string{"name": "web-1"})
+    event := b.Build()
+    roundTrip(t, b.JSON(), event)
+
+    var request map[string]string
+    if err := event.Cts.DecodeRequest(&request); err != nil {
+        t.Fatal(err)
+    }
+    if request["name"] != "web-1" {
+        t.Errorf("request = %v", request)
+    }
+    if !event.Matches(cts.Filter{ServiceTypes: []string{"ecs"}, TraceNames: []string{"createServer"}}) {
+        t.Errorf("event %+v does not match its own service and trace", event.Cts)
+    }
+}
+
+func TestDDSBuilder(t *testing.T) {
+    b := NewDDSEvent().
+        WithNamespace("shop", "orders").
+        WithOperation(dds.OperationInsert, map[string]string{"_id": "o1"}).
+        WithDocument(map[string]interface{}{"_id": "o1", "total": 3})
+    event := b.Build()
+    roundTrip(t, b.JSON(), event)
+
+    change := event.Records[0].Dds
+    ns, err := change.Namespace()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if ns.DB != "shop" || ns.Collection != "orders" {
+        t.Errorf("namespace = %+v", ns)
+    }
+    id, err := change.DocumentID()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if id != "o1" {
+        t.Errorf("document id = %v", id)
+    }
+}
+
+func TestTimerBuilder(t *testing.T) {
+    scheduled := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    b := NewTimerEvent().
+        WithTriggerName("nightly").
+        WithTime(scheduled).
+        WithUserEvent(map[string]string{"job": "cleanup"})
+    event := b.Build()
+    roundTrip(t, b.JSON(), event)
+
+    got, err := event.ScheduledTime()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !got.Equal(scheduled) || event.TriggerName != "nightly" {
+        t.Errorf("event = %+v", event)
+    }
+    userEvent, err := timer.UserEventAs[map[string]string](&event)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if userEvent["job"] != "cleanup" {
+        t.Errorf("user event = %v", userEvent)
+    }
+}
+
+func TestAPIGBuilder(t *testing.T) {
+    b := NewAPIGEvent("POST", "/sites/demo").
+        WithJSONBody(map[string]string{"theme": "twentytwentyfour"}).
+        WithHeader("X-Request-Id", "r1").
+        WithQuery("dry_run", "true").
+        WithPathParameter("name", "demo")
+    event := b.Build()
+    roundTrip(t, b.JSON(), event)
+
+    if event.HttpMethod != "POST" || event.Path != "/sites/demo" {
+        t.Errorf("event = %+v", event)
+    }
+    if event.Headers["x-request-id"] != "r1" || event.QueryStringParameters["dry_run"] != "true" || event.PathParameters["name"] != "demo" {
+        t.Errorf("headers = %v, query = %v, path = %v", event.Headers, event.QueryStringParameters, event.PathParameters)
+    }
+    req, err := apig.NewHTTPRequest(context.Background(), &event)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var body map[string]string
+    if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body["theme"] != "twentytwentyfour" {
+        t.Errorf("body = %v, err = %v", body, err)
+    }
+    if apig.PathParameter(req, "name") != "demo" || req.URL.Query().Get("dry_run") != "true" {
+        t.Errorf("request = %+v", req)
+    }
+}
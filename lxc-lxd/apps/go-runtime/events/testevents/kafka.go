@@ -0,0 +1,55 @@
+package testevents
+
+import (
+    "time"
+
+    "huaweicloud.com/go-runtime/events/kafka"
+)
+
+type KafkaEventBuilder struct {
+    event kafka.KAFKATriggerEvent
+}
+
+// NewKafkaEvent starts from the kafka fixture, one topic with two messages.
+func NewKafkaEvent() *KafkaEventBuilder {
+    b := &KafkaEventBuilder{}
+    mustDecodeFixture(FixtureKafka, &b.event)
+    return b
+}
+
+func (b *KafkaEventBuilder) WithInstanceID(instanceID string) *KafkaEventBuilder {
+    b.event.InstanceId = instanceID
+    return b
+}
+
+func (b *KafkaEventBuilder) WithRegion(region string) *KafkaEventBuilder {
+    b.event.Region = region
+    return b
+}
+
+func (b *KafkaEventBuilder) WithEventTime(t time.Time) *KafkaEventBuilder {
+    b.event.EventTime = t.Unix()
+    return b
+}
+
+// WithMessages replaces all records with a single record of topicID.
+func (b *KafkaEventBuilder) WithMessages(topicID string, messages ...string) *KafkaEventBuilder {
+    b.event.Records = nil
+    return b.AddRecord(topicID, messages...)
+}
+
+func (b *KafkaEventBuilder) AddRecord(topicID string, messages ...string) *KafkaEventBuilder {
+    b.event.Records = append(b.event.Records, kafka.KAFKARecord{
+        Messages: append([]string{}, messages...),
+        TopicId:  topicID,
+    })
+    return b
+}
+
+func (b *KafkaEventBuilder) Build() kafka.KAFKATriggerEvent {
+    return b.event
+}
+
+func (b *KafkaEventBuilder) JSON() []byte {
+    return mustMarshal(b.event)
+}
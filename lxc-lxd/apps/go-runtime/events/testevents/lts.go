@@ -0,0 +1,89 @@
+package testevents
+
+import (
+    "bytes"
+    "compress/gzip"
+    "encoding/base64"
+    "fmt"
+    "time"
+
+    "huaweicloud.com/go-runtime/events/lts"
+)
+
+// ltsLog and ltsData mirror the document LTS base64-encodes into lts.data.
+type ltsLog struct {
+    Message  string `json:"message"`
+    Time     int64  `json:"time"`
+    HostName string `json:"host_name"`
+    IP       string `json:"ip"`
+    Path     string `json:"path"`
+    LogUID   string `json:"log_uid"`
+    LineNo   int    `json:"line_no"`
+}
+
+type ltsData struct {
+    Logs       string `json:"logs"`
+    Owner      string `json:"owner"`
+    LogGroupID string `json:"log_group_id"`
+    LogTopicID string `json:"log_topic_id"`
+}
+
+type LTSEventBuilder struct {
+    event lts.LTSTriggerEvent
+}
+
+// NewLTSEvent starts from the lts fixture, a single log line.
+func NewLTSEvent() *LTSEventBuilder {
+    b := &LTSEventBuilder{}
+    mustDecodeFixture(FixtureLTS, &b.event)
+    return b
+}
+
+// WithData sets the raw document carried in lts.data.
+func (b *LTSEventBuilder) WithData(data []byte) *LTSEventBuilder {
+    b.event.Lts.Data = base64.StdEncoding.EncodeToString(data)
+    return b
+}
+
+// WithLogs sets lts.data to a document of the given log group and stream
+// holding one log line per message, all stamped with t.
+func (b *LTSEventBuilder) WithLogs(logGroupID, logStreamID string, t time.Time, messages ...string) *LTSEventBuilder {
+    logs := make([]ltsLog, 0, len(messages))
+    for i, message := range messages {
+        logs = append(logs, ltsLog{
+            Message:  message,
+            Time:     t.UnixMilli(),
+            HostName: "ecs-testevents",
+            IP:       "192.168.0.1",
+            Path:     "/var/log/app/app.log",
+            LineNo:   i + 1,
+        })
+    }
+    return b.WithData(mustMarshal(ltsData{
+        Logs:       string(mustMarshal(logs)),
+        LogGroupID: logGroupID,
+        LogTopicID: logStreamID,
+    }))
+}
+
+// Gzipped compresses the current data, as LTS does for large batches.
+func (b *LTSEventBuilder) Gzipped() *LTSEventBuilder {
+    data, err := b.event.Lts.GetRawData()
+    if err != nil {
+        panic(fmt.Sprintf("testevents: decode lts data: %s", err))
+    }
+    var compressed bytes.Buffer
+    writer := gzip.NewWriter(&compressed)
+    writer.Write(data)
+    writer.Close()
+    b.event.Lts.Data = base64.StdEncoding.EncodeToString(compressed.Bytes())
+    return b
+}
+
+func (b *LTSEventBuilder) Build() lts.LTSTriggerEvent {
+    return b.event
+}
+
+func (b *LTSEventBuilder) JSON() []byte {
+    return mustMarshal(b.event)
+}
@@ -0,0 +1,76 @@
+package testevents
+
+import (
+    "huaweicloud.com/go-runtime/events/smn"
+)
+
+type SMNEventBuilder struct {
+    event smn.SMNTriggerEvent
+}
+
+// NewSMNEvent starts from the smn fixture, a single notification record.
+func NewSMNEvent() *SMNEventBuilder {
+    b := &SMNEventBuilder{}
+    mustDecodeFixture(FixtureSMN, &b.event)
+    return b
+}
+
+// body returns the record the With* methods apply to, the last one.
+func (b *SMNEventBuilder) body() *smn.SMNBody {
+    return &b.event.Record[len(b.event.Record)-1].Smn
+}
+
+// WithMessage sets the message of the record. Strings and byte slices
+// are used as is, any other value is JSON encoded the way the publisher of
+// a structured message would.
+func (b *SMNEventBuilder) WithMessage(message interface{}) *SMNEventBuilder {
+    switch message := message.(type) {
+    case string:
+        b.body().Message = message
+    case []byte:
+        b.body().Message = string(message)
+    default:
+        b.body().Message = string(mustMarshal(message))
+    }
+    return b
+}
+
+func (b *SMNEventBuilder) WithSubject(subject string) *SMNEventBuilder {
+    b.body().Subject = subject
+    return b
+}
+
+func (b *SMNEventBuilder) WithTopicURN(topicURN string) *SMNEventBuilder {
+    b.body().TopicUrn = topicURN
+    return b
+}
+
+func (b *SMNEventBuilder) WithMessageID(messageID string) *SMNEventBuilder {
+    b.body().MessageId = messageID
+    return b
+}
+
+func (b *SMNEventBuilder) WithMessageAttribute(key, value string) *SMNEventBuilder {
+    if b.body().MessageAttributes == nil {
+        b.body().MessageAttributes = map[string]string{}
+    }
+    b.body().MessageAttributes[key] = value
+    return b
+}
+
+// AddRecord appends a fresh fixture record, the following With* calls apply
+// to it.
+func (b *SMNEventBuilder) AddRecord() *SMNEventBuilder {
+    var fixture smn.SMNTriggerEvent
+    mustDecodeFixture(FixtureSMN, &fixture)
+    b.event.Record = append(b.event.Record, fixture.Record[0])
+    return b
+}
+
+func (b *SMNEventBuilder) Build() smn.SMNTriggerEvent {
+    return b.event
+}
+
+func (b *SMNEventBuilder) JSON() []byte {
+    return mustMarshal(b.event)
+}
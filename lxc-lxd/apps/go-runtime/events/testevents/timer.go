@@ -0,0 +1,47 @@
+package testevents
+
+import (
+    "time"
+
+    "huaweicloud.com/go-runtime/events/timer"
+)
+
+type TimerEventBuilder struct {
+    event timer.TimerTriggerEvent
+}
+
+// NewTimerEvent starts from the timer fixture.
+func NewTimerEvent() *TimerEventBuilder {
+    b := &TimerEventBuilder{}
+    mustDecodeFixture(FixtureTimer, &b.event)
+    return b
+}
+
+func (b *TimerEventBuilder) WithTriggerName(triggerName string) *TimerEventBuilder {
+    b.event.TriggerName = triggerName
+    return b
+}
+
+func (b *TimerEventBuilder) WithTime(t time.Time) *TimerEventBuilder {
+    b.event.Time = t.Format(time.RFC3339)
+    return b
+}
+
+// WithUserEvent sets the user event, JSON encoding anything that is not
+// already a string.
+func (b *TimerEventBuilder) WithUserEvent(userEvent interface{}) *TimerEventBuilder {
+    if s, ok := userEvent.(string); ok {
+        b.event.UserEvent = s
+    } else {
+        b.event.UserEvent = string(mustMarshal(userEvent))
+    }
+    return b
+}
+
+func (b *TimerEventBuilder) Build() timer.TimerTriggerEvent {
+    return b.event
+}
+
+func (b *TimerEventBuilder) JSON() []byte {
+    return mustMarshal(b.event)
+}
@@ -1,7 +1,9 @@
 package timer
 
 import (
+    "encoding/json"
     "fmt"
+    "time"
 )
 
 type TimerTriggerEvent struct {
@@ -12,6 +14,28 @@ type TimerTriggerEvent struct {
     UserEvent string `json:"user_event"`
 }
 
+// ScheduledTime parses Time, the RFC 3339 time the trigger fired for.
+func (e *TimerTriggerEvent) ScheduledTime() (time.Time, error) {
+    t, err := time.Parse(time.RFC3339, e.Time)
+    if err != nil {
+        return time.Time{}, fmt.Errorf("invalid timer time %q: %w", e.Time, err)
+    }
+    return t, nil
+}
+
+// UserEventAs decodes the user event configured on the trigger as JSON into
+// a T. An empty user event decodes to the zero T.
+func UserEventAs[T any](e *TimerTriggerEvent) (T, error) {
+    var v T
+    if e.UserEvent == "" {
+        return v, nil
+    }
+    if err := json.Unmarshal([]byte(e.UserEvent), &v); err != nil {
+        return v, fmt.Errorf("decode user event of timer %s: %w", e.TriggerName, err)
+    }
+    return v, nil
+}
+
 func (e *TimerTriggerEvent) String() string {
     return fmt.Sprintf(`TimerTriggerEvent{
                                  version=%v,
@@ -20,4 +44,4 @@ func (e *TimerTriggerEvent) String() string {
                                  trigger_type=%v,
                                  user_event=%v
                                }`, e.Version, e.Time, e.TriggerName, e.TriggerType, e.UserEvent)
-}
\ No newline at end of file
+}
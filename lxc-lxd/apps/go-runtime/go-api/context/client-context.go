@@ -1,6 +1,8 @@
 package context
 
 import (
+	stdcontext "context"
+
 	"huaweicloud.com/go-runtime/pkg/runtime/common"
 )
 
@@ -38,4 +40,26 @@ type RuntimeContext interface {
 	GetToken() string
 	
 	GetSecurityToken() string
+
+	// GetInvokeType returns "sync" or "async" from X-CFF-Invoke-Type.
+	GetInvokeType() string
+
+	// Credentials returns the credentials of this invocation: the temporary
+	// X-CFF-Security-* keys of the function's agency when set, else the
+	// X-CFF-Access-Key/X-CFF-Secret-Key headers, else the ACCESS_KEY,
+	// SECRET_KEY and SECURITY_TOKEN environment variables.
+	Credentials() common.CredentialsProvider
+
+	// GetMetrics returns the registry for custom handler metrics.
+	GetMetrics() common.Metrics
+
+	// GetInFlightInvocations returns how many invocations of this instance
+	// are running the handler, this one included, see RUNTIME_MAX_CONCURRENCY.
+	GetInFlightInvocations() int
+
+	// GetContext returns a context that is cancelled when the function
+	// timeout elapses, when RUNTIME_TIMEOUT is set. Handlers must return
+	// once it is done: a timed-out handler is not stopped and keeps its
+	// concurrency slot until it returns.
+	GetContext() stdcontext.Context
 }
@@ -0,0 +1,165 @@
+package runtime
+
+import (
+    stdcontext "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+
+    "huaweicloud.com/go-runtime/events/dis"
+    "huaweicloud.com/go-runtime/events/kafka"
+    "huaweicloud.com/go-runtime/events/smn"
+    "huaweicloud.com/go-runtime/go-api/context"
+    "huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
+    "huaweicloud.com/go-runtime/pkg/runtime/tracing"
+)
+
+const ErrorTypeBatchFailure = "BatchFailure"
+
+// RecordHandlerFunc processes a single record of a batch. Returning an error
+// fails only that record; a non-retryable fnhandler.UserError marks it as not
+// worth retrying.
+type RecordHandlerFunc[T any] func(ctx stdcontext.Context, record T, rtCtx context.RuntimeContext) error
+
+// KafkaMessage is one message of a Kafka batch. Index is its position across
+// all records of the event and identifies it in a BatchResult.
+type KafkaMessage struct {
+    TopicID string
+    Index   int
+    Value   string
+}
+
+// DISRecordData is one record of a DIS batch with its base64-decoded data.
+// Index is its position in the event.
+type DISRecordData struct {
+    *dis.DISRecord
+    Index int
+    Data  []byte
+}
+
+type BatchFailure struct {
+    ID        string `json:"id"`
+    Error     string `json:"error"`
+    Retryable bool   `json:"retryable"`
+}
+
+// BatchResult reports the outcome of every record of a batch. Failed lists
+// the records that did not succeed, identified by SMN message_id, Kafka
+// message index or DIS sequence number. The platform redelivers a failed
+// event as a whole, so records that already succeeded run again unless fn is
+// made idempotent, e.g. with IdempotentRecord.
+type BatchResult struct {
+    Total  int            `json:"total"`
+    Failed []BatchFailure `json:"failed"`
+}
+
+// FailedIDs returns the identifiers of the failed records.
+func (r *BatchResult) FailedIDs() []string {
+    ids := make([]string, 0, len(r.Failed))
+    for _, failure := range r.Failed {
+        ids = append(ids, failure.ID)
+    }
+    return ids
+}
+
+// Retryable reports whether any failed record may succeed on redelivery.
+func (r *BatchResult) Retryable() bool {
+    for _, failure := range r.Failed {
+        if failure.Retryable {
+            return true
+        }
+    }
+    return false
+}
+
+func (r *BatchResult) record(ctx context.RuntimeContext, id string, err error) {
+    r.Total++
+    if err == nil {
+        ctx.GetLogger().Debugf("record %s processed", id)
+        return
+    }
+    ctx.GetLogger().Warnf("record %s failed: %s", id, err)
+    r.Failed = append(r.Failed, BatchFailure{ID: id, Error: err.Error(), Retryable: fnhandler.IsRetryable(err)})
+}
+
+// result returns r when every record succeeded and otherwise a UserError
+// carrying r as details, retryable when any failed record is.
+func (r *BatchResult) result(ctx context.RuntimeContext) (*BatchResult, error) {
+    ctx.GetLogger().Infof("batch processed: %d records, %d failed", r.Total, len(r.Failed))
+    if len(r.Failed) == 0 {
+        return r, nil
+    }
+    msg := fmt.Sprintf("%d of %d records failed", len(r.Failed), r.Total)
+    return r, fnhandler.NewUserError(http.StatusInternalServerError, ErrorTypeBatchFailure, msg, r).WithRetryable(r.Retryable())
+}
+
+// SMNBatch adapts fn into a handler that processes the records of an SMN
+// event one by one, see BatchResult. Records are identified by message_id and
+// each is traced with tracing.StartSMNRecord.
+func SMNBatch(fn RecordHandlerFunc[smn.SMNRecord]) fnhandler.TypedHandlerFunc[smn.SMNTriggerEvent, *BatchResult] {
+    return func(ctx stdcontext.Context, event smn.SMNTriggerEvent, rtCtx context.RuntimeContext) (*BatchResult, error) {
+        result := &BatchResult{}
+        for _, record := range event.Record {
+            recordCtx, span := tracing.StartSMNRecord(ctx, &record.Smn)
+            err := runRecord(recordCtx, fn, record, rtCtx)
+            tracing.EndSpan(span, err)
+            result.record(rtCtx, record.Smn.MessageId, err)
+        }
+        return result.result(rtCtx)
+    }
+}
+
+// KafkaBatch adapts fn into a handler that processes the messages of a Kafka
+// event one by one, see BatchResult. Messages are identified by their index
+// across the event.
+func KafkaBatch(fn RecordHandlerFunc[KafkaMessage]) fnhandler.TypedHandlerFunc[kafka.KAFKATriggerEvent, *BatchResult] {
+    return func(ctx stdcontext.Context, event kafka.KAFKATriggerEvent, rtCtx context.RuntimeContext) (*BatchResult, error) {
+        result := &BatchResult{}
+        index := 0
+        for _, record := range event.Records {
+            for _, message := range record.Messages {
+                msg := KafkaMessage{TopicID: record.TopicId, Index: index, Value: message}
+                result.record(rtCtx, strconv.Itoa(index), runRecord(ctx, fn, msg, rtCtx))
+                index++
+            }
+        }
+        return result.result(rtCtx)
+    }
+}
+
+// DISBatch adapts fn into a handler that processes the records of a DIS event
+// one by one with dis.DISTriggerEvent.ForEachRecord, see BatchResult. Records
+// are identified by sequence number and fail without calling fn when their
+// data is not valid base64.
+func DISBatch(fn RecordHandlerFunc[DISRecordData]) fnhandler.TypedHandlerFunc[dis.DISTriggerEvent, *BatchResult] {
+    return func(ctx stdcontext.Context, event dis.DISTriggerEvent, rtCtx context.RuntimeContext) (*BatchResult, error) {
+        err := event.ForEachRecord(func(index int, record *dis.DISRecord, data []byte) error {
+            return runRecord(ctx, fn, DISRecordData{DISRecord: record, Index: index, Data: data}, rtCtx)
+        })
+        var recordErrs dis.RecordErrors
+        errors.As(err, &recordErrs)
+        failed := make(map[int]error, len(recordErrs))
+        for _, recordErr := range recordErrs {
+            failed[recordErr.Index] = recordErr.Err
+        }
+
+        result := &BatchResult{}
+        for i, record := range event.Message.Records {
+            result.record(rtCtx, record.SequenceNumber, failed[i])
+        }
+        return result.result(rtCtx)
+    }
+}
+
+// runRecord calls fn unless the invocation is already past its deadline, in
+// which case the remaining records fail as retryable.
+func runRecord[T any](ctx stdcontext.Context, fn RecordHandlerFunc[T], record T, rtCtx context.RuntimeContext) error {
+    if err := ctx.Err(); err != nil {
+        if errors.Is(err, stdcontext.DeadlineExceeded) {
+            return fnhandler.NewUserError(http.StatusGatewayTimeout, fnhandler.ErrorTypeTimeout, "invocation deadline exceeded", nil)
+        }
+        return fnhandler.NewUserError(http.StatusServiceUnavailable, fnhandler.ErrorTypeRetryable, err.Error(), nil)
+    }
+    return fn(ctx, record, rtCtx)
+}
@@ -1,11 +1,72 @@
 package common
 
 import (
+    "crypto/rand"
+    "errors"
+    "fmt"
     "net/http"
+    "time"
+)
+
+// Headers of the invocation that the runtime synthesizes when missing, see
+// NewInvokeRequest.
+const (
+    HeaderRequestID  = "X-CFF-Request-Id"
+    HeaderInvokeType = "X-CFF-Invoke-Type"
 )
 
 type RuntimeLogger interface {
+    // Logf logs at info level, kept for handlers written before the
+    // leveled methods existed.
     Logf(format string, args ...interface{})
+    Debugf(format string, args ...interface{})
+    Infof(format string, args ...interface{})
+    Warnf(format string, args ...interface{})
+    Errorf(format string, args ...interface{})
+}
+
+// Metrics records custom handler metrics, served together with the runtime
+// metrics on RUNTIME_METRICS_ADDR. Labels are key, value pairs, e.g.
+//
+//     ctx.GetMetrics().AddCounter("dns_records_created_total", 1, "zone", zone)
+type Metrics interface {
+    AddCounter(name string, value float64, labels ...string)
+    SetGauge(name string, value float64, labels ...string)
+    ObserveHistogram(name string, value float64, labels ...string)
+}
+
+// Sources of Credentials, from most to least preferred.
+const (
+    CredentialsSourceSecurityHeaders = "X-CFF-Security-*"
+    CredentialsSourceHeaders         = "X-CFF-*-Key"
+    CredentialsSourceEnv             = "env"
+)
+
+var ErrNoCredentials = errors.New("no credentials in the invocation headers or the ACCESS_KEY/SECRET_KEY environment")
+
+// Credentials are the keys a function calls Huawei Cloud APIs with. They are
+// plain values, the runtime does not depend on the Huawei Cloud SDK; build
+// an SDK credential from them whenever they change, e.g.
+//
+//     basic.NewCredentialsBuilder().WithAk(c.AK).WithSk(c.SK).
+//         WithSecurityToken(c.SecurityToken).WithProjectId(c.ProjectId).SafeBuild()
+type Credentials struct {
+    AK            string
+    SK            string
+    SecurityToken string
+    ProjectId     string
+    // Source is one of the CredentialsSource constants.
+    Source string
+}
+
+// Temporary reports whether the credentials expire, i.e. carry a security
+// token, and so must be retrieved again on every invocation.
+func (c Credentials) Temporary() bool {
+    return c.SecurityToken != ""
+}
+
+type CredentialsProvider interface {
+    Retrieve() (Credentials, error)
 }
 
 type InvokeResponse struct {
@@ -18,6 +79,41 @@ type InvokeRequest struct {
     Header http.Header
 }
 
+// NewInvokeRequest builds the request the FunctionGraph host would send with
+// payload and a copy of header, synthesizing a missing X-CFF-Request-Id and
+// an X-CFF-Invoke-Type of "sync". Transports other than the host's net/rpc
+// use it so handlers always see both headers.
+func NewInvokeRequest(payload []byte, header http.Header) *InvokeRequest {
+    header = header.Clone()
+    if header == nil {
+        header = http.Header{}
+    }
+    if header.Get(HeaderRequestID) == "" {
+        header.Set(HeaderRequestID, newRequestID())
+    }
+    if header.Get(HeaderInvokeType) == "" {
+        header.Set(HeaderInvokeType, "sync")
+    }
+    return &InvokeRequest{Payload: payload, Header: header}
+}
+
+// newRequestID returns a random ID in the UUID form used by the host.
+func newRequestID() string {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return fmt.Sprintf("request-%d", time.Now().UnixNano())
+    }
+    return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+type InitializeRequest struct {
+    Header http.Header
+}
+
+type InitializeResponse struct {
+    StatusCode int
+}
+
 type HealthCheckRequest struct {
 }
 
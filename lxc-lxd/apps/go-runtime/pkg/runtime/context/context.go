@@ -1,5 +1,10 @@
 package rtcontext
 
+import (
+    "context"
+    "log/slog"
+)
+
 type ContextEnv struct {
     rtProjectID   string
     rtFcName      string
@@ -8,6 +13,7 @@ type ContextEnv struct {
     rtMemory      int
     rtCPU         int
     rtTimeout     int
+    rtTimeoutSet  bool
     rtHanlder     string
     rtUserData    map[string]string
     rtInitializerTimeout     int
@@ -24,23 +30,17 @@ type ContextHTTP struct {
     fcStartTime   int64
     rtRemainTime  int
     securityToken string
+    invokeType    string
 }
 
 type ContextProvider struct {
     ctxEnv      *ContextEnv
     ctxHTTPHead *ContextHTTP
+    ctx         context.Context
+    logger      *userFunctionLog
+    inflight    func() int
 }
 
 type userFunctionLog struct {
-    requestID string
-}
-
-func (l *userFunctionLog) getRequestId() string {
-    return l.requestID
+    logger *slog.Logger
 }
-
-func (l *userFunctionLog) setRequestId(requestId string) {
-    l.requestID = requestId
-}
-
-
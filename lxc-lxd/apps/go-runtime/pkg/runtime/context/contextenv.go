@@ -26,6 +26,9 @@ func getCurrentTime() int64 {
 
 func (contextObj *ContextEnv) InitiliazeContext() {
     timeout := os.Getenv("RUNTIME_TIMEOUT")
+    // Only an explicit RUNTIME_TIMEOUT is enforced as a deadline, the default
+    // is merely reported, e.g. by GetRemainingTimeInMilliSeconds.
+    contextObj.rtTimeoutSet = timeout != ""
     if timeout == "" {
         timeout = defaultTimeout
     }
@@ -65,10 +68,15 @@ func (contextObj *ContextEnv) InitiliazeContext() {
     InitializerHandler := os.Getenv("RUNTIME_INITIALIZER_HANDLER")
     if InitializerHandler != "" {
         contextObj.rtInitializerHanlder = InitializerHandler
-        InitializerTimeout := os.Getenv("RUNTIME_INITIALIZER_TIMEOUT")
-        if InitializerTimeout == "" {
-            InitializerTimeout = defaultTimeout
-        }
+    }
+    // The timeout is read even without RUNTIME_INITIALIZER_HANDLER, since an
+    // initializer can also be set with runtime.RegisterInitializer. Such an
+    // initializer has no timeout unless RUNTIME_INITIALIZER_TIMEOUT is set.
+    InitializerTimeout := os.Getenv("RUNTIME_INITIALIZER_TIMEOUT")
+    if InitializerTimeout == "" && InitializerHandler != "" {
+        InitializerTimeout = defaultTimeout
+    }
+    if InitializerTimeout != "" {
         contextObj.rtInitializerTimeout = atoi(InitializerTimeout)
     }
 }
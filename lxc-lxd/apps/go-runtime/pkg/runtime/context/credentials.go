@@ -0,0 +1,43 @@
+package rtcontext
+
+import (
+    "os"
+
+    "huaweicloud.com/go-runtime/pkg/runtime/common"
+)
+
+// contextCredentials retrieves the credentials of one invocation. Temporary
+// keys are delivered in the headers of every invocation, so retrieving them
+// from a fresh ContextProvider is what refreshes them.
+type contextCredentials struct {
+    ctxEnv      *ContextEnv
+    ctxHTTPHead *ContextHTTP
+}
+
+func (c contextCredentials) Retrieve() (common.Credentials, error) {
+    creds := common.Credentials{ProjectId: c.ctxEnv.rtProjectID}
+    head := c.ctxHTTPHead
+    switch {
+    case head.securityAccessKey != "" && head.securitySecretKey != "":
+        creds.AK = head.securityAccessKey
+        creds.SK = head.securitySecretKey
+        creds.SecurityToken = head.securityToken
+        creds.Source = common.CredentialsSourceSecurityHeaders
+    case head.accesskey != "" && head.secretKey != "":
+        creds.AK = head.accesskey
+        creds.SK = head.secretKey
+        creds.Source = common.CredentialsSourceHeaders
+    case os.Getenv("ACCESS_KEY") != "" && os.Getenv("SECRET_KEY") != "":
+        creds.AK = os.Getenv("ACCESS_KEY")
+        creds.SK = os.Getenv("SECRET_KEY")
+        creds.SecurityToken = os.Getenv("SECURITY_TOKEN")
+        creds.Source = common.CredentialsSourceEnv
+    default:
+        return common.Credentials{}, common.ErrNoCredentials
+    }
+    return creds, nil
+}
+
+func (ctxProvider ContextProvider) Credentials() common.CredentialsProvider {
+    return contextCredentials{ctxEnv: ctxProvider.ctxEnv, ctxHTTPHead: ctxProvider.ctxHTTPHead}
+}
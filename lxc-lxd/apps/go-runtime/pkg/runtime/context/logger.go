@@ -0,0 +1,90 @@
+package rtcontext
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "os"
+    "strings"
+    "sync"
+
+    "huaweicloud.com/go-runtime/pkg/runtime/redact"
+)
+
+const (
+    logFormatText = "text"
+    logFormatJSON = "json"
+)
+
+var (
+    logHandlerOnce sync.Once
+    logHandler     slog.Handler
+)
+
+// getLogHandler returns the handler shared by all function loggers. The
+// output format is selected by RUNTIME_LOG_FORMAT (text or json) and the
+// minimum level by RUNTIME_LOG_LEVEL (debug, info, warn or error). Secrets
+// are masked before anything is written, see package redact.
+func getLogHandler() slog.Handler {
+    logHandlerOnce.Do(func() {
+        options := &slog.HandlerOptions{Level: parseLogLevel(os.Getenv("RUNTIME_LOG_LEVEL"))}
+        switch strings.ToLower(os.Getenv("RUNTIME_LOG_FORMAT")) {
+        case logFormatJSON:
+            logHandler = slog.NewJSONHandler(os.Stdout, options)
+        default:
+            logHandler = slog.NewTextHandler(os.Stdout, options)
+        }
+        logHandler = redact.NewHandler(logHandler)
+    })
+    return logHandler
+}
+
+func parseLogLevel(value string) slog.Level {
+    var level slog.Level
+    if value == "" {
+        return slog.LevelInfo
+    }
+    if err := level.UnmarshalText([]byte(value)); err != nil {
+        return slog.LevelInfo
+    }
+    return level
+}
+
+// newUserFunctionLog returns a logger bound to one invocation, so concurrent
+// invocations never share a request ID.
+func newUserFunctionLog(ctxEnv *ContextEnv, ctxHTTPHead *ContextHTTP) *userFunctionLog {
+    logger := slog.New(getLogHandler()).With(
+        slog.String("request_id", ctxHTTPHead.requestID),
+        slog.String("function_name", ctxEnv.rtFcName),
+        slog.String("function_version", ctxEnv.rtFcVersion),
+        slog.String("invoke_type", ctxHTTPHead.invokeType),
+    )
+    return &userFunctionLog{logger: logger}
+}
+
+func (l *userFunctionLog) logf(level slog.Level, format string, args ...interface{}) {
+    if !l.logger.Enabled(context.Background(), level) {
+        return
+    }
+    l.logger.Log(context.Background(), level, fmt.Sprintf(format, args...))
+}
+
+func (l *userFunctionLog) Logf(format string, args ...interface{}) {
+    l.logf(slog.LevelInfo, format, args...)
+}
+
+func (l *userFunctionLog) Debugf(format string, args ...interface{}) {
+    l.logf(slog.LevelDebug, format, args...)
+}
+
+func (l *userFunctionLog) Infof(format string, args ...interface{}) {
+    l.logf(slog.LevelInfo, format, args...)
+}
+
+func (l *userFunctionLog) Warnf(format string, args ...interface{}) {
+    l.logf(slog.LevelWarn, format, args...)
+}
+
+func (l *userFunctionLog) Errorf(format string, args ...interface{}) {
+    l.logf(slog.LevelError, format, args...)
+}
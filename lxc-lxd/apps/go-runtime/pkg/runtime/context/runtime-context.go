@@ -1,18 +1,17 @@
 package rtcontext
 
 import (
-    "fmt"
+    "context"
     "sync"
     "time"
 
     "huaweicloud.com/go-runtime/pkg/runtime/common"
+    "huaweicloud.com/go-runtime/pkg/runtime/metrics"
 )
 
 var (
     once       sync.Once
     contextobj *ContextEnv
-
-    funcLogger = &userFunctionLog{}
 )
 
 func (ctxProvider ContextProvider) GetRemainingTimeInMilliSeconds() int {
@@ -27,6 +26,56 @@ func (ctxProvider ContextProvider) GetRemainingTimeInMilliSeconds() int {
     return ctxProvider.ctxHTTPHead.rtRemainTime
 }
 
+// GetDeadline returns the time at which the invocation exceeds
+// RUNTIME_TIMEOUT, or the zero time when RUNTIME_TIMEOUT is not set. The
+// invocation then fails with a 504 FunctionTimeout, but Go cannot stop the
+// handler: it keeps running, and keeps its RUNTIME_MAX_CONCURRENCY slot,
+// until it returns, so handlers must honour GetContext().Done().
+func (ctxProvider ContextProvider) GetDeadline() time.Time {
+    if !ctxProvider.ctxEnv.rtTimeoutSet || ctxProvider.ctxEnv.rtTimeout <= 0 {
+        return time.Time{}
+    }
+    startTime := time.Unix(0, ctxProvider.ctxHTTPHead.fcStartTime*int64(time.Millisecond))
+    return startTime.Add(time.Duration(ctxProvider.ctxEnv.rtTimeout) * time.Second)
+}
+
+// GetInitializerDeadline returns the time at which the initializer exceeds
+// RUNTIME_INITIALIZER_TIMEOUT, or the zero time when no timeout is configured.
+func (ctxProvider ContextProvider) GetInitializerDeadline() time.Time {
+    if ctxProvider.ctxEnv.rtInitializerTimeout <= 0 {
+        return time.Time{}
+    }
+    startTime := time.Unix(0, ctxProvider.ctxHTTPHead.fcStartTime*int64(time.Millisecond))
+    return startTime.Add(time.Duration(ctxProvider.ctxEnv.rtInitializerTimeout) * time.Second)
+}
+
+func (ctxProvider ContextProvider) GetContext() context.Context {
+    if ctxProvider.ctx == nil {
+        return context.Background()
+    }
+    return ctxProvider.ctx
+}
+
+// WithContext returns a copy of the provider whose GetContext returns ctx.
+func (ctxProvider ContextProvider) WithContext(ctx context.Context) ContextProvider {
+    ctxProvider.ctx = ctx
+    return ctxProvider
+}
+
+// WithInFlight returns a copy of the provider whose GetInFlightInvocations
+// returns inflight().
+func (ctxProvider ContextProvider) WithInFlight(inflight func() int) ContextProvider {
+    ctxProvider.inflight = inflight
+    return ctxProvider
+}
+
+func (ctxProvider ContextProvider) GetInFlightInvocations() int {
+    if ctxProvider.inflight == nil {
+        return 0
+    }
+    return ctxProvider.inflight()
+}
+
 func (ctxProvider ContextProvider) GetFunctionName() string {
     return ctxProvider.ctxEnv.rtFcName
 }
@@ -55,11 +104,10 @@ func (ctxProvider ContextProvider) GetUserData(key string) string {
 }
 
 func (ctxProvider ContextProvider) GetLogger() common.RuntimeLogger {
-    if funcLogger.getRequestId() != ctxProvider.ctxHTTPHead.requestID {
-        funcLogger.setRequestId(ctxProvider.ctxHTTPHead.requestID)
+    if ctxProvider.logger == nil {
+        return newUserFunctionLog(ctxProvider.ctxEnv, ctxProvider.ctxHTTPHead)
     }
-
-    return funcLogger
+    return ctxProvider.logger
 }
 
 func (ctxProvider ContextProvider) GetProjectID() string {
@@ -78,6 +126,10 @@ func (ctxProvider ContextProvider) GetInitializerHandler() string {
     return ctxProvider.ctxEnv.rtInitializerHanlder
 }
 
+func (ctxProvider ContextProvider) GetInitializerTimeout() int {
+    return ctxProvider.ctxEnv.rtInitializerTimeout
+}
+
 func (ctxProvider ContextProvider) GetAccessKey() string {
     return ctxProvider.ctxHTTPHead.accesskey
 }
@@ -106,15 +158,20 @@ func (ctxProvider ContextProvider) GetSecurityToken() string {
     return ctxProvider.ctxHTTPHead.securityToken
 }
 
-func (logger *userFunctionLog) Logf(format string, args ...interface{}) {
-    logTimeFormat := "2006-01-02 15:04:05.999-07:00"
-    content := fmt.Sprintf(format, args...)
-    myFormat := fmt.Sprintf("%s %s %s", time.Now().Format(logTimeFormat), logger.requestID, content)
-    fmt.Println(myFormat)
+func (ctxProvider ContextProvider) GetInvokeType() string {
+    return ctxProvider.ctxHTTPHead.invokeType
+}
+
+func (ctxProvider ContextProvider) GetMetrics() common.Metrics {
+    return metrics.Default
 }
 
 func GetContextProvider(ctxEnv *ContextEnv, ctxHTTPHead *ContextHTTP) ContextProvider {
-    return ContextProvider{ctxEnv, ctxHTTPHead}
+    return ContextProvider{
+        ctxEnv:      ctxEnv,
+        ctxHTTPHead: ctxHTTPHead,
+        logger:      newUserFunctionLog(ctxEnv, ctxHTTPHead),
+    }
 }
 
 func GetContextEnvInstance() *ContextEnv {
@@ -156,5 +213,9 @@ func GetContextHTTPHeadInstance(req *common.InvokeRequest) *ContextHTTP {
     if securityToken != "" {
         contextHTTPHead.securityToken = securityToken
     }
+    contextHTTPHead.invokeType = req.Header.Get("X-CFF-Invoke-Type")
+    if contextHTTPHead.invokeType == "" {
+        contextHTTPHead.invokeType = "sync"
+    }
     return contextHTTPHead
 }
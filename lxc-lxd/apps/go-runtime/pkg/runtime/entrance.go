@@ -1,29 +1,106 @@
 package runtime
 
 import (
-    "huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
-    "log"
+    "context"
+    "errors"
+    "fmt"
     "net/http"
-    "net/rpc"
+    "huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
+    "huaweicloud.com/go-runtime/pkg/runtime/local"
+    "huaweicloud.com/go-runtime/pkg/runtime/tracing"
     "os"
+    "os/signal"
+    "syscall"
+)
+
+var (
+    initializer fnhandler.InitializerFunc
+    middlewares []fnhandler.Middleware
 )
 
+// RegisterInitializer sets a function that runs once per instance before the
+// first invocation, bounded by RUNTIME_INITIALIZER_TIMEOUT. It must be called
+// before Register.
+func RegisterInitializer(fn fnhandler.InitializerFunc) {
+    initializer = fn
+}
+
+// Use adds middlewares around the registered handler, the first one being the
+// outermost. It must be called before Register.
+func Use(middleware ...fnhandler.Middleware) {
+    middlewares = append(middlewares, middleware...)
+}
+
 // Valid function signatures:
 // 	func Handler(payload []byte, ctx context.RuntimeContext) (interface{}, error)
-func Register(handler interface{}) {
+func Register(handler interface{}) error {
     wrappedHandler := fnhandler.NewHandler(handler)
-    RegisterHandler(wrappedHandler)
+    return RegisterHandler(wrappedHandler)
 }
 
-func RegisterHandler(handler fnhandler.IRequestHandler) {
-    err := rpc.Register(fnhandler.NewFunction(handler))
-    if err != nil {
-        log.Fatal("failed to register handler function")
+// RegisterTyped registers a handler whose payload is JSON decoded into E
+// before it is called, e.g.
+// 	func Handler(ctx context.Context, event smn.SMNTriggerEvent, rtCtx fgcontext.RuntimeContext) (string, error)
+// Payloads that fail to decode or validate are rejected with a 400 InvokeError.
+func RegisterTyped[E, R any](handler fnhandler.TypedHandlerFunc[E, R]) error {
+    return RegisterHandler(fnhandler.NewTypedHandler(handler))
+}
+
+// RegisterHTTP serves a plain net/http handler behind an APIG trigger. Each
+// event is converted into an *http.Request and the handler's output into an
+// APIGTriggerResponse, see apig.ServeHTTP.
+func RegisterHTTP(handler http.Handler) error {
+    return RegisterTyped(httpHandlerFunc(handler))
+}
+
+// RegisterHandler serves handler on RUNTIME_API_ADDR, over the transport
+// selected by RUNTIME_TRANSPORT, until the process receives SIGTERM or SIGINT
+// and then shuts down gracefully, see Server. When the binary is started as
+// `<binary> local ...` the handler is invoked once with a local event
+// instead, see package local, and a failed invocation is returned as a
+// *local.ExitError after the shutdown hooks have run. Spans are exported as configured by the
+// OTEL_* environment, see tracing.Setup, and flushed before exiting.
+func RegisterHandler(handler fnhandler.IRequestHandler) error {
+    if fault, ok := handler.(*fnhandler.FaultRequestHandler); ok {
+        return fmt.Errorf("failed to register handler function: %w", fault.Err())
+    }
+
+    options := []fnhandler.FunctionOption{fnhandler.WithMiddleware(middlewares...)}
+    if initializer != nil {
+        options = append(options, fnhandler.WithInitializer(initializer))
     }
-    rpc.HandleHTTP()
-    runtimeAddr := os.Getenv("RUNTIME_API_ADDR")
-    err = http.ListenAndServe(runtimeAddr, nil)
+    function := fnhandler.NewFunction(handler, options...)
+
+    shutdownTracing, err := tracing.Setup(context.Background())
     if err != nil {
-        log.Fatal(err)
+        return fmt.Errorf("failed to set up tracing: %w", err)
+    }
+
+    server := NewServer(function)
+    server.Hooks = append(server.Hooks, shutdownTracing)
+    if len(os.Args) > 1 && os.Args[1] == local.Command {
+        code := local.Main(function, os.Args[2:], os.Stdout, os.Stderr)
+        var err error
+        if code != 0 {
+            err = &local.ExitError{Code: code}
+        }
+        return errors.Join(err, server.Shutdown())
     }
-}
\ No newline at end of file
+
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+    defer stop()
+    return server.ListenAndServe(ctx)
+}
+
+// ExitCode returns the process exit code for an error returned by Register:
+// 0 for nil, the code of a *local.ExitError, else 1.
+func ExitCode(err error) int {
+    if err == nil {
+        return 0
+    }
+    var exitErr *local.ExitError
+    if errors.As(err, &exitErr) {
+        return exitErr.Code
+    }
+    return 1
+}
@@ -0,0 +1,26 @@
+package runtime
+
+import (
+    "errors"
+    "fmt"
+    "testing"
+
+    "huaweicloud.com/go-runtime/pkg/runtime/local"
+)
+
+func TestExitCode(t *testing.T) {
+    tests := []struct {
+        err  error
+        want int
+    }{
+        {nil, 0},
+        {errors.New("listen failed"), 1},
+        {&local.ExitError{Code: 2}, 2},
+        {errors.Join(&local.ExitError{Code: 1}, fmt.Errorf("shutdown hook failed: %w", errors.New("smtp"))), 1},
+    }
+    for _, test := range tests {
+        if got := ExitCode(test.err); got != test.want {
+            t.Errorf("ExitCode(%v) = %d, want %d", test.err, got, test.want)
+        }
+    }
+}
@@ -0,0 +1,129 @@
+package fnhandler
+
+import (
+    "context"
+    "log"
+    "net/http"
+    "os"
+    "strconv"
+    "time"
+)
+
+const errorTypeTooManyInvocations = "TooManyInvocations"
+
+var (
+    maxConcurrency = envInt("RUNTIME_MAX_CONCURRENCY")
+    maxQueueSize   = envInt("RUNTIME_MAX_QUEUE_SIZE")
+    queueTimeout   = time.Duration(envInt("RUNTIME_QUEUE_TIMEOUT")) * time.Second
+)
+
+func envInt(name string) int {
+    value := os.Getenv(name)
+    if value == "" {
+        return 0
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil || n < 0 {
+        log.Printf("env '%s'(%s) invalid.", name, value)
+        return 0
+    }
+    return n
+}
+
+// WithMaxConcurrency lets at most limit invocations run the handler at once,
+// overriding RUNTIME_MAX_CONCURRENCY. Further invocations wait for a free
+// slot; they are rejected with a 429 InvokeError when queueSize of them are
+// already waiting (RUNTIME_MAX_QUEUE_SIZE) or after waiting for timeout
+// (RUNTIME_QUEUE_TIMEOUT, in seconds). A zero limit disables the limit, a
+// zero queueSize or timeout means no bound other than the invocation's own
+// deadline. A handler that outlives its invocation's timeout keeps its slot
+// until it returns.
+func WithMaxConcurrency(limit, queueSize int, timeout time.Duration) FunctionOption {
+    return func(fn *Function) {
+        fn.setMaxConcurrency(limit, queueSize, timeout)
+    }
+}
+
+func (fn *Function) setMaxConcurrency(limit, queueSize int, timeout time.Duration) {
+    fn.slots = nil
+    if limit > 0 {
+        fn.slots = make(chan struct{}, limit)
+    }
+    fn.maxQueueSize = queueSize
+    fn.queueTimeout = timeout
+}
+
+// acquireSlot waits until the invocation may run the handler. The slot must
+// be returned with releaseSlot once the handler has returned, which may be
+// after the invocation timed out.
+func (fn *Function) acquireSlot(ctx context.Context) *InvokeError {
+    if err := fn.waitSlot(ctx); err != nil {
+        return err
+    }
+    fn.inflightMu.Lock()
+    fn.running++
+    fn.inflightMu.Unlock()
+    runningInvocations.Add(1)
+    return nil
+}
+
+func (fn *Function) waitSlot(ctx context.Context) *InvokeError {
+    if fn.slots == nil {
+        return nil
+    }
+    select {
+    case fn.slots <- struct{}{}:
+        return nil
+    default:
+    }
+
+    fn.inflightMu.Lock()
+    if fn.maxQueueSize > 0 && fn.queued >= fn.maxQueueSize {
+        fn.inflightMu.Unlock()
+        return newTooManyInvocationsError("Function instance is at its maximum concurrency.")
+    }
+    fn.queued++
+    fn.inflightMu.Unlock()
+    queuedInvocations.Add(1)
+    defer func() {
+        fn.inflightMu.Lock()
+        fn.queued--
+        fn.inflightMu.Unlock()
+        queuedInvocations.Add(-1)
+    }()
+
+    var timeout <-chan time.Time
+    if fn.queueTimeout > 0 {
+        timer := time.NewTimer(fn.queueTimeout)
+        defer timer.Stop()
+        timeout = timer.C
+    }
+    select {
+    case fn.slots <- struct{}{}:
+        return nil
+    case <-timeout:
+    case <-ctx.Done():
+    }
+    return newTooManyInvocationsError("Function instance is at its maximum concurrency, timed out waiting for a free slot.")
+}
+
+func (fn *Function) releaseSlot() {
+    fn.inflightMu.Lock()
+    fn.running--
+    fn.notifyIdleLocked()
+    fn.inflightMu.Unlock()
+    runningInvocations.Add(-1)
+    if fn.slots != nil {
+        <-fn.slots
+    }
+}
+
+func newTooManyInvocationsError(message string) *InvokeError {
+    return newInvokeError(http.StatusTooManyRequests, errorTypeTooManyInvocations, message, true)
+}
+
+// InFlightInvocations returns the number of invocations currently running
+// the handler, not counting those waiting for a concurrency slot.
+func (fn *Function) InFlightInvocations() int {
+    return int(fn.inflightCount())
+}
@@ -0,0 +1,170 @@
+package fnhandler
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    fgcontext "huaweicloud.com/go-runtime/go-api/context"
+    "huaweicloud.com/go-runtime/pkg/runtime/common"
+)
+
+// blockingHandler returns a handler that signals started when it runs and
+// returns once release is closed or receives a value.
+func blockingHandler() (handler IRequestHandler, started chan struct{}, release chan struct{}) {
+    started = make(chan struct{}, 16)
+    release = make(chan struct{})
+    handler = HandlerFunc(func(_ []byte, _ fgcontext.RuntimeContext) (interface{}, error) {
+        started <- struct{}{}
+        <-release
+        return "done", nil
+    })
+    return handler, started, release
+}
+
+// invokeAsync invokes fn in a goroutine and returns the channel its error
+// is sent to.
+func invokeAsync(fn *Function) <-chan error {
+    result := make(chan error, 1)
+    go func() {
+        _, err := invokeFunction(fn, []byte("{}"), nil)
+        result <- err
+    }()
+    return result
+}
+
+// waitFor polls cond until it holds or fails the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+    t.Helper()
+    deadline := time.Now().Add(5 * time.Second)
+    for !cond() {
+        if time.Now().After(deadline) {
+            t.Fatalf("timed out waiting for %s", what)
+        }
+        time.Sleep(time.Millisecond)
+    }
+}
+
+func (fn *Function) counts() (inflight, running, queued int) {
+    fn.inflightMu.Lock()
+    defer fn.inflightMu.Unlock()
+    return fn.inflight, fn.running, fn.queued
+}
+
+func requireTooManyInvocations(t *testing.T, err error, message string) {
+    t.Helper()
+    var invokeErr *InvokeError
+    if !errors.As(err, &invokeErr) || invokeErr.ErrorCode != http.StatusTooManyRequests || invokeErr.ErrorType != errorTypeTooManyInvocations {
+        t.Fatalf("err = %v, want a 429 %s", err, errorTypeTooManyInvocations)
+    }
+    if !invokeErr.Retryable || !strings.Contains(invokeErr.ErrorMsg, message) {
+        t.Errorf("error %s is not retryable or does not mention %q", invokeErr.ErrorMsg, message)
+    }
+}
+
+func TestMaxConcurrencyQueue(t *testing.T) {
+    handler, started, release := blockingHandler()
+    fn := NewFunction(handler, WithMaxConcurrency(1, 1, 0))
+
+    first := invokeAsync(fn)
+    <-started
+    if got := fn.InFlightInvocations(); got != 1 {
+        t.Errorf("InFlightInvocations() = %d, want 1", got)
+    }
+
+    second := invokeAsync(fn)
+    waitFor(t, "the second invocation to queue", func() bool {
+        _, _, queued := fn.counts()
+        return queued == 1
+    })
+    select {
+    case <-started:
+        t.Fatal("second invocation ran the handler beyond the concurrency limit")
+    default:
+    }
+
+    // The queue is full, a third invocation is rejected right away.
+    _, err := invokeFunction(fn, []byte("{}"), nil)
+    requireTooManyInvocations(t, err, "maximum concurrency")
+
+    release <- struct{}{}
+    if err := <-first; err != nil {
+        t.Fatalf("first invocation: %s", err)
+    }
+    <-started
+    release <- struct{}{}
+    if err := <-second; err != nil {
+        t.Fatalf("queued invocation: %s", err)
+    }
+
+    waitFor(t, "the slots to be released", func() bool {
+        inflight, running, queued := fn.counts()
+        return inflight == 0 && running == 0 && queued == 0
+    })
+}
+
+func TestMaxConcurrencyQueueTimeout(t *testing.T) {
+    handler, started, release := blockingHandler()
+    defer close(release)
+    fn := NewFunction(handler, WithMaxConcurrency(1, 0, 20*time.Millisecond))
+
+    first := invokeAsync(fn)
+    <-started
+
+    start := time.Now()
+    _, err := invokeFunction(fn, []byte("{}"), nil)
+    requireTooManyInvocations(t, err, "timed out waiting for a free slot")
+    if waited := time.Since(start); waited < 20*time.Millisecond {
+        t.Errorf("rejected after %s, want it to wait for the queue timeout", waited)
+    }
+
+    release <- struct{}{}
+    if err := <-first; err != nil {
+        t.Fatalf("first invocation: %s", err)
+    }
+}
+
+func TestMaxConcurrencyQueuedInvocationCanceled(t *testing.T) {
+    handler, started, release := blockingHandler()
+    defer close(release)
+    fn := NewFunction(handler, WithMaxConcurrency(1, 0, 0))
+
+    first := invokeAsync(fn)
+    <-started
+
+    ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+    defer cancel()
+    req := &common.InvokeRequest{Payload: []byte("{}"), Header: http.Header{}}
+    err := fn.invoke(ctx, req, &common.InvokeResponse{})
+    requireTooManyInvocations(t, err, "timed out waiting for a free slot")
+    if _, _, queued := fn.counts(); queued != 0 {
+        t.Errorf("queued = %d after the waiting invocation gave up, want 0", queued)
+    }
+
+    release <- struct{}{}
+    if err := <-first; err != nil {
+        t.Fatalf("first invocation: %s", err)
+    }
+}
+
+func TestUnlimitedConcurrency(t *testing.T) {
+    handler, started, release := blockingHandler()
+    fn := NewFunction(handler, WithMaxConcurrency(0, 0, 0))
+
+    results := []<-chan error{invokeAsync(fn), invokeAsync(fn), invokeAsync(fn)}
+    for range results {
+        <-started
+    }
+    if got := fn.InFlightInvocations(); got != len(results) {
+        t.Errorf("InFlightInvocations() = %d, want %d", got, len(results))
+    }
+    close(release)
+    for _, result := range results {
+        if err := <-result; err != nil {
+            t.Fatal(err)
+        }
+    }
+}
@@ -0,0 +1,72 @@
+package fnhandler
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+)
+
+// beginInvoke records an invocation as in flight, it returns false once the
+// function is draining.
+func (fn *Function) beginInvoke() bool {
+    fn.inflightMu.Lock()
+    defer fn.inflightMu.Unlock()
+    if fn.draining {
+        return false
+    }
+    fn.inflight++
+    return true
+}
+
+func (fn *Function) endInvoke() {
+    fn.inflightMu.Lock()
+    defer fn.inflightMu.Unlock()
+    fn.inflight--
+    fn.notifyIdleLocked()
+}
+
+// isIdleLocked reports whether no invocation is in flight and no handler is
+// running. A handler keeps running after its invocation timed out, until it
+// returns and releases its slot.
+func (fn *Function) isIdleLocked() bool {
+    return fn.inflight == 0 && fn.running == 0
+}
+
+// notifyIdleLocked wakes up Drain once the function is idle.
+func (fn *Function) notifyIdleLocked() {
+    if fn.isIdleLocked() && fn.idle != nil {
+        close(fn.idle)
+        fn.idle = nil
+    }
+}
+
+// Drain stops the function from accepting new invocations and waits until
+// the in-flight ones have returned and their handlers, including those of
+// timed-out invocations, have finished, or until ctx is done.
+func (fn *Function) Drain(ctx context.Context) error {
+    fn.inflightMu.Lock()
+    fn.draining = true
+    if fn.isIdleLocked() {
+        fn.inflightMu.Unlock()
+        return nil
+    }
+    if fn.idle == nil {
+        fn.idle = make(chan struct{})
+    }
+    idle := fn.idle
+    fn.inflightMu.Unlock()
+
+    select {
+    case <-idle:
+        return nil
+    case <-ctx.Done():
+        fn.inflightMu.Lock()
+        inflight, running := fn.inflight, fn.running
+        fn.inflightMu.Unlock()
+        return fmt.Errorf("%d invocations in flight and %d handlers still running after shutdown grace period: %w", inflight, running, ctx.Err())
+    }
+}
+
+func newShuttingDownError() *InvokeError {
+    return newInvokeError(http.StatusServiceUnavailable, "FunctionShuttingDown", "Function instance is shutting down.", true)
+}
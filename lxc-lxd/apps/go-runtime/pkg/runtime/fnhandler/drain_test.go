@@ -0,0 +1,116 @@
+package fnhandler
+
+import (
+    stdcontext "context"
+    "errors"
+    "net/http"
+    "testing"
+    "time"
+
+    "huaweicloud.com/go-runtime/go-api/context"
+    "huaweicloud.com/go-runtime/pkg/runtime/common"
+)
+
+func requireShuttingDown(t *testing.T, err error) {
+    t.Helper()
+    var invokeErr *InvokeError
+    if !errors.As(err, &invokeErr) || invokeErr.ErrorCode != http.StatusServiceUnavailable || invokeErr.ErrorType != "FunctionShuttingDown" {
+        t.Fatalf("err = %v, want a 503 FunctionShuttingDown", err)
+    }
+}
+
+func TestDrainWaitsForRunningHandlers(t *testing.T) {
+    handler, started, release := blockingHandler()
+    fn := NewFunction(handler)
+
+    // The first invocation times out, but its handler keeps running.
+    ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), 10*time.Millisecond)
+    defer cancel()
+    req := &common.InvokeRequest{Payload: []byte("{}"), Header: http.Header{}}
+    if err := fn.invoke(ctx, req, &common.InvokeResponse{}); err == nil {
+        t.Fatal("invocation did not time out")
+    }
+    <-started
+    second := invokeAsync(fn)
+    <-started
+
+    drained := make(chan error, 1)
+    go func() { drained <- fn.Drain(stdcontext.Background()) }()
+    waitFor(t, "Drain to start", func() bool {
+        fn.inflightMu.Lock()
+        defer fn.inflightMu.Unlock()
+        return fn.draining
+    })
+
+    _, err := invokeFunction(fn, []byte("{}"), nil)
+    requireShuttingDown(t, err)
+    requireShuttingDown(t, fn.Initialize(&common.InitializeRequest{Header: http.Header{}}, &common.InitializeResponse{}))
+
+    release <- struct{}{}
+    release <- struct{}{}
+    if err := <-second; err != nil {
+        t.Fatalf("in-flight invocation: %s", err)
+    }
+    select {
+    case err := <-drained:
+        if err != nil {
+            t.Fatalf("Drain: %s", err)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("Drain did not return once the handlers finished")
+    }
+}
+
+func TestDrainGracePeriod(t *testing.T) {
+    handler, started, release := blockingHandler()
+    defer close(release)
+    fn := NewFunction(handler)
+
+    first := invokeAsync(fn)
+    <-started
+
+    ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), 10*time.Millisecond)
+    defer cancel()
+    err := fn.Drain(ctx)
+    if !errors.Is(err, stdcontext.DeadlineExceeded) {
+        t.Fatalf("Drain() = %v, want the grace period exceeded", err)
+    }
+
+    release <- struct{}{}
+    if err := <-first; err != nil {
+        t.Fatalf("in-flight invocation: %s", err)
+    }
+}
+
+func TestDrainWaitsForInitializer(t *testing.T) {
+    initStarted, initRelease := make(chan struct{}), make(chan struct{})
+    fn := NewFunction(HandlerFunc(func(_ []byte, _ context.RuntimeContext) (interface{}, error) {
+        return nil, nil
+    }), WithInitializer(func(context.RuntimeContext) error {
+        close(initStarted)
+        <-initRelease
+        return nil
+    }))
+
+    initialized := make(chan error, 1)
+    go func() {
+        initialized <- fn.Initialize(&common.InitializeRequest{Header: http.Header{}}, &common.InitializeResponse{})
+    }()
+    <-initStarted
+
+    drained := make(chan error, 1)
+    go func() { drained <- fn.Drain(stdcontext.Background()) }()
+    select {
+    case <-drained:
+        t.Fatal("Drain returned while the initializer was running")
+    case <-time.After(20 * time.Millisecond):
+    }
+
+    close(initRelease)
+    if err := <-initialized; err != nil {
+        t.Fatalf("Initialize: %s", err)
+    }
+    if err := <-drained; err != nil {
+        t.Fatalf("Drain: %s", err)
+    }
+}
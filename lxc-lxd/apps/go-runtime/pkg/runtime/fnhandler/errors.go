@@ -0,0 +1,88 @@
+package fnhandler
+
+import (
+    "errors"
+    "net/http"
+)
+
+// Error types for UserError. Handlers may also use their own types.
+const (
+    ErrorTypeValidation = "ValidationError"
+    ErrorTypeUpstream   = "UpstreamError"
+    ErrorTypeTimeout    = "TimeoutError"
+    ErrorTypeRetryable  = "RetryableError"
+)
+
+// UserError is an error returned by a handler that Invoke reports with the
+// chosen status code and type instead of a generic 500 FunctionReturnError.
+type UserError struct {
+    Code      int
+    Type      string
+    Message   string
+    Details   interface{}
+    Retryable bool
+}
+
+// NewUserError returns an error reported with the given HTTP status code,
+// type and message. details, when not nil, is marshalled into the error
+// message. Upstream, timeout and retryable errors and 5xx codes are
+// retryable by default, see WithRetryable.
+func NewUserError(code int, errType, msg string, details interface{}) *UserError {
+    if code < http.StatusBadRequest || code > 599 {
+        code = http.StatusInternalServerError
+    }
+    retryable := code >= http.StatusInternalServerError
+    switch errType {
+    case ErrorTypeUpstream, ErrorTypeTimeout, ErrorTypeRetryable:
+        retryable = true
+    case ErrorTypeValidation:
+        retryable = false
+    }
+    return &UserError{
+        Code:      code,
+        Type:      errType,
+        Message:   msg,
+        Details:   details,
+        Retryable: retryable,
+    }
+}
+
+func (e *UserError) Error() string {
+    return e.Message
+}
+
+// WithRetryable overrides whether the invocation should be retried. An async
+// invocation failing with a non-retryable error is acknowledged so that the
+// trigger, e.g. an SMN subscription, does not deliver it again.
+func (e *UserError) WithRetryable(retryable bool) *UserError {
+    e.Retryable = retryable
+    return e
+}
+
+func (e *UserError) toInvokeError() *InvokeError {
+    return &InvokeError{
+        ErrorCode: e.Code,
+        ErrorType: e.Type,
+        ErrorMsg:  marshalErrorMessage(&InvokeErrorMessage{
+            ErrorMessage: hideAbsolutePath(e.Message),
+            ErrorType:    e.Type,
+            Details:      e.Details,
+            Retryable:    &e.Retryable,
+        }),
+        Retryable: e.Retryable,
+    }
+}
+
+// IsRetryable reports whether err, or an error it wraps, is a UserError or
+// InvokeError marked as retryable. Other errors are considered retryable.
+func IsRetryable(err error) bool {
+    var userErr *UserError
+    if errors.As(err, &userErr) {
+        return userErr.Retryable
+    }
+    var invokeErr *InvokeError
+    if errors.As(err, &invokeErr) {
+        return invokeErr.Retryable
+    }
+    return err != nil
+}
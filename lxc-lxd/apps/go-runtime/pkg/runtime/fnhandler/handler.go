@@ -9,6 +9,7 @@ import (
     "strings"
 
     "huaweicloud.com/go-runtime/go-api/context"
+    "huaweicloud.com/go-runtime/pkg/runtime/redact"
 )
 
 var (
@@ -35,6 +36,8 @@ func (e *FunctionLoadFailedError) Error() string {
     return e.ErrorMessage
 }
 
+// hideAbsolutePath strips build and runtime directories from path and masks
+// secrets, it is applied to everything reported back to the caller.
 func hideAbsolutePath(path string) string {
     result := path
     if strings.Contains(path, goRootSrcPath) {
@@ -63,7 +66,7 @@ func hideAbsolutePath(path string) string {
         result = result[index:]
     }
 
-    return result
+    return redact.String(result)
 }
 
 func formatFuncName(name string) string {
@@ -82,6 +85,10 @@ func formatFuncName(name string) string {
 // HandlerFunc implements Handler.
 type HandlerFunc func([]byte, context.RuntimeContext) (interface{}, error)
 
+func (f HandlerFunc) Handle(payload []byte, ctx context.RuntimeContext) (interface{}, error) {
+    return f(payload, ctx)
+}
+
 type IRequestHandler interface {
     Handle(payload []byte, ctx context.RuntimeContext) (interface{}, error)
 }
@@ -109,6 +116,12 @@ func NewHandler(handlerFunc interface{}) IRequestHandler {
             ErrorMessage: fmt.Sprintf("handler kind %s is not %s", handlerType.Kind(), reflect.Func),
         }}
     }
+    if err := validateHandlerType(handlerType); err != nil {
+        return &FaultRequestHandler{err: &FunctionLoadFailedError{
+            StatusCode:   http.StatusBadRequest,
+            ErrorMessage: err.Error(),
+        }}
+    }
 
     return &RequestHandler{handlerFunc: HandlerFunc(func(payload []byte, ctx context.RuntimeContext) (interface{}, error){
         var args []reflect.Value
@@ -127,10 +140,44 @@ func NewHandler(handlerFunc interface{}) IRequestHandler {
     })}
 }
 
+var (
+    payloadType = reflect.TypeOf([]byte(nil))
+    runtimeContextType = reflect.TypeOf((*context.RuntimeContext)(nil)).Elem()
+    errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// validateHandlerType checks that handlerType matches
+// func([]byte, context.RuntimeContext) (interface{}, error) so that a bad
+// handler is reported when it is registered rather than on the first Call.
+func validateHandlerType(handlerType reflect.Type) error {
+    if handlerType.NumIn() != 2 {
+        return fmt.Errorf("handler takes %d arguments, expected 2", handlerType.NumIn())
+    }
+    if handlerType.In(0) != payloadType {
+        return fmt.Errorf("handler first argument is %s, expected %s", handlerType.In(0), payloadType)
+    }
+    ctxArg := handlerType.In(1)
+    if ctxArg.Kind() != reflect.Interface || !runtimeContextType.Implements(ctxArg) {
+        return fmt.Errorf("handler second argument is %s, expected %s", ctxArg, runtimeContextType)
+    }
+    if handlerType.NumOut() != 2 {
+        return fmt.Errorf("handler returns %d values, expected 2", handlerType.NumOut())
+    }
+    if handlerType.Out(1) != errorType {
+        return fmt.Errorf("handler second return value is %s, expected %s", handlerType.Out(1), errorType)
+    }
+    return nil
+}
+
 type FaultRequestHandler struct {
     err *FunctionLoadFailedError
 }
 
+// Err returns the reason the handler could not be loaded.
+func (handler *FaultRequestHandler) Err() error {
+    return handler.err
+}
+
 func (handler *FaultRequestHandler) Handle(payload []byte, ctx context.RuntimeContext) (interface{}, error) {
     return nil, handler.err
 }
@@ -0,0 +1,102 @@
+package fnhandler
+
+import (
+    stdcontext "context"
+    "errors"
+    "fmt"
+    "net/http"
+
+    "huaweicloud.com/go-runtime/go-api/context"
+    "huaweicloud.com/go-runtime/pkg/runtime/common"
+    "huaweicloud.com/go-runtime/pkg/runtime/context"
+)
+
+// InitializerFunc prepares state shared by all invocations, such as API
+// clients or tokens. It runs once per instance before the first invocation.
+type InitializerFunc func(ctx context.RuntimeContext) error
+
+// WithInitializer sets the function run by Initialize.
+func WithInitializer(initializer InitializerFunc) FunctionOption {
+    return func(fn *Function) {
+        fn.initializer = initializer
+    }
+}
+
+// Initialize runs the initializer once, bounded by RUNTIME_INITIALIZER_TIMEOUT.
+// Later calls return the result of the first one. Like Invoke, it is rejected
+// once the function is draining and Drain waits for it to return.
+func (fn *Function) Initialize(req *common.InitializeRequest, resp *common.InitializeResponse) error {
+    if !fn.beginInvoke() {
+        return newShuttingDownError()
+    }
+    defer fn.endInvoke()
+
+    if err := fn.initialize(req.Header); err != nil {
+        return err
+    }
+    resp.StatusCode = http.StatusOK
+    return nil
+}
+
+func (fn *Function) initialize(header http.Header) error {
+    if fn.initializer == nil {
+        return nil
+    }
+    fn.initOnce.Do(func() {
+        fn.initErr = fn.runInitializerWithTimeout(header)
+    })
+    return fn.initErr
+}
+
+func (fn *Function) runInitializerWithTimeout(header http.Header) error {
+    contextHTTPHeaderObj := rtcontext.GetContextHTTPHeadInstance(&common.InvokeRequest{Header: header})
+    contextProvider := rtcontext.GetContextProvider(contextObj, contextHTTPHeaderObj)
+
+    var ctx stdcontext.Context
+    var cancel stdcontext.CancelFunc
+    if deadline := contextProvider.GetInitializerDeadline(); !deadline.IsZero() {
+        ctx, cancel = stdcontext.WithDeadline(stdcontext.Background(), deadline)
+    } else {
+        ctx, cancel = stdcontext.WithCancel(stdcontext.Background())
+    }
+    defer cancel()
+    contextProvider = contextProvider.WithContext(ctx)
+
+    done := make(chan error, 1)
+    go func() {
+        done <- fn.runInitializer(contextProvider)
+    }()
+
+    select {
+    case err := <-done:
+        if err == nil {
+            return nil
+        }
+        var invokeErr *InvokeError
+        if errors.As(err, &invokeErr) {
+            return invokeErr
+        }
+        var userErr *UserError
+        if errors.As(err, &userErr) {
+            contextProvider.GetLogger().Errorf("initialize function failed: %s: %s", userErr.Type, hideAbsolutePath(userErr.Message))
+            return userErr.toInvokeError()
+        }
+        errorMessage := hideAbsolutePath(err.Error())
+        contextProvider.GetLogger().Errorf("initialize function failed: %s", errorMessage)
+        return newInvokeError(http.StatusInternalServerError, "InitializerReturnError", errorMessage, true)
+    case <-ctx.Done():
+        errorMessage := fmt.Sprintf("Function initializer exceeded the timeout of %d seconds.", contextProvider.GetInitializerTimeout())
+        contextProvider.GetLogger().Errorf("%s", errorMessage)
+        return newInvokeError(http.StatusGatewayTimeout, "InitializerTimeout", errorMessage, true)
+    }
+}
+
+func (fn *Function) runInitializer(contextProvider rtcontext.ContextProvider) (funcErr error) {
+    defer func() {
+        if e := recover(); e != nil {
+            funcErr, _ = newPanicError(e, contextProvider, invokeTypeSync)
+        }
+    }()
+
+    return fn.initializer(contextProvider)
+}
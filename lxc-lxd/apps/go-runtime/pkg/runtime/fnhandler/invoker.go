@@ -1,17 +1,20 @@
 package fnhandler
 
 import (
-    "bytes"
+    "context"
     "encoding/json"
+    "errors"
     "fmt"
     "huaweicloud.com/go-runtime/pkg/runtime/common"
     "huaweicloud.com/go-runtime/pkg/runtime/context"
+    "huaweicloud.com/go-runtime/pkg/runtime/redact"
     "log"
     "net/http"
     "os"
-    "runtime"
     "strconv"
     "strings"
+    "sync"
+    "time"
 )
 
 const (
@@ -20,10 +23,14 @@ const (
     invokeTypeSync = "sync"
     invokeTypeAsync = "async"
     errmsgBadRequestParameters = "bad request parameters"
+    errorTypePanic = "panic"
+    errorTypeFunctionTimeout = "FunctionTimeout"
 )
 
 var (
     maxResponseBodySize = 0
+    responseSpillDir = os.Getenv("RUNTIME_RESPONSE_SPILL_DIR")
+    responseSpillTTL = time.Duration(envInt("RUNTIME_RESPONSE_SPILL_TTL")) * time.Second
     EmptyStringBytes = []byte("")
     contextObj = rtcontext.GetContextEnvInstance()
 )
@@ -33,7 +40,7 @@ func init() {
     if len(maxResponseBodySizeEnvValue) > 0 {
         value, err := strconv.Atoi(maxResponseBodySizeEnvValue)
         if err != nil {
-            log.Println("env 'RUNTIME_MAX_RESP_BODY_SIZE'(%s) invalid.", maxResponseBodySizeEnvValue)
+            log.Printf("env 'RUNTIME_MAX_RESP_BODY_SIZE'(%s) invalid.", maxResponseBodySizeEnvValue)
         } else {
             maxResponseBodySize = value
         }
@@ -52,11 +59,26 @@ type InvokeErrorMessage struct {
     ErrorMessage string   `json:"errorMessage"`
     ErrorType    string   `json:"errorType,omitempty"`
     StackTrace   []string `json:"stackTrace,omitempty"`
+    Details      interface{} `json:"details,omitempty"`
+    // Retryable is only set for UserError, the net/rpc host only receives
+    // this message and not the InvokeError fields.
+    Retryable    *bool    `json:"retryable,omitempty"`
 }
 
 type InvokeError struct {
     ErrorCode int `json:"error_code"`
+    ErrorType string `json:"error_type,omitempty"`
     ErrorMsg string `json:"error_msg"`
+    Retryable bool `json:"retryable"`
+}
+
+func newInvokeError(errCode int, errType, errMessage string, retryable bool) *InvokeError {
+    return &InvokeError{
+        ErrorCode: errCode,
+        ErrorType: errType,
+        ErrorMsg:  makeErrorMessage(errMessage, errType, nil),
+        Retryable: retryable,
+    }
 }
 
 func (e *InvokeError) Error() string {
@@ -64,6 +86,10 @@ func (e *InvokeError) Error() string {
 }
 
 func makeErrorMessage(errMessage, errType string, stacks []*stack) string {
+    return makeErrorMessageWithDetails(errMessage, errType, stacks, nil)
+}
+
+func makeErrorMessageWithDetails(errMessage, errType string, stacks []*stack, details interface{}) string {
     var stackTraces []string
     if len(stacks) > 0 {
         stackTraces = make([]string, 0)
@@ -73,43 +99,92 @@ func makeErrorMessage(errMessage, errType string, stacks []*stack) string {
         }
     }
 
-    m := &InvokeErrorMessage{
+    return marshalErrorMessage(&InvokeErrorMessage{
         ErrorMessage: errMessage,
         ErrorType: errType,
         StackTrace: stackTraces,
-    }
+        Details: details,
+    })
+}
 
+func marshalErrorMessage(m *InvokeErrorMessage) string {
     data, err := json.MarshalIndent(m, "", "    ")
     if err != nil {
         log.Println("marshal error message failed.")
         return ""
     }
 
-    return string(data)
+    return redact.String(string(data))
 }
 
 func makePanicMessage(message string, stackTrace []*stack) (string) {
-    return makeErrorMessage(message, "panic", stackTrace)
+    return makeErrorMessage(message, errorTypePanic, stackTrace)
+}
+
+// isHandlerBoundary reports whether funcName belongs to the runtime code
+// that called the user function, where panic stack traces stop.
+func isHandlerBoundary(funcName string) bool {
+    return strings.Contains(funcName, "go-runtime/pkg/runtime/fnhandler.")
 }
 
 type Function struct {
     handler IRequestHandler
+
+    initializer InitializerFunc
+    initOnce    sync.Once
+    initErr     error
+
+    middlewares []Middleware
+
+    responseStore ResponseStore
+
+    slots        chan struct{}
+    maxQueueSize int
+    queueTimeout time.Duration
+
+    inflightMu sync.Mutex
+    inflight   int
+    running    int
+    queued     int
+    draining   bool
+    idle       chan struct{}
 }
 
-func NewFunction(handler IRequestHandler) *Function {
-    return &Function{handler: handler}
+// FunctionOption configures a Function created by NewFunction.
+type FunctionOption func(*Function)
+
+// NewFunction returns a Function calling handler through the configured
+// middlewares. PanicRecovery is always the outermost middleware, so a panic
+// is returned as an InvokeError even when no middleware recovers it, and a
+// StreamFunc or io.Reader result is written out right inside it.
+func NewFunction(handler IRequestHandler, options ...FunctionOption) *Function {
+    fn := &Function{}
+    fn.setMaxConcurrency(maxConcurrency, maxQueueSize, queueTimeout)
+    if responseSpillDir != "" {
+        ttl := responseSpillTTL
+        if ttl == 0 {
+            ttl = defaultResponseSpillTTL
+        }
+        fn.responseStore = DirResponseStore{Dir: responseSpillDir, TTL: ttl}
+    }
+    for _, option := range options {
+        option(fn)
+    }
+    fn.handler = Chain(handler, append([]Middleware{PanicRecovery(), fn.writeResponse}, fn.middlewares...)...)
+    return fn
 }
 
-func (fn *Function) Invoke(req *common.InvokeRequest, resp *common.InvokeResponse) (funcErr error) {
+func (fn *Function) invoke(parent context.Context, req *common.InvokeRequest, resp *common.InvokeResponse) error {
+    if !fn.beginInvoke() {
+        return newShuttingDownError()
+    }
+    defer fn.endInvoke()
+
     invokeType := req.Header.Get(headerCFFInvokeType)
     if len(invokeType) == 0 {
         invokeType = invokeTypeSync
     }
 
-    contextHTTPHeaderObj := rtcontext.GetContextHTTPHeadInstance(req)
-    contextProvider := rtcontext.GetContextProvider(contextObj, contextHTTPHeaderObj)
-
-    var err error
     var payload []byte
     if req.Payload != nil {
         payload = req.Payload
@@ -120,66 +195,70 @@ func (fn *Function) Invoke(req *common.InvokeRequest, resp *common.InvokeRespons
         }
     }
 
-    defer func() {
-        if e := recover(); e != nil {
-            contextProvider.GetLogger().Logf("invoke function  failed for function code panic, error=%+v.", e)
-            var panicBuffer bytes.Buffer
-
-            invokeErr := &InvokeError{
-                ErrorCode: 555,
-            }
-            stackTrace := make([]*stack, 0)
-            stackCount := 0
-            for skip := 1; ; skip++ {
-                pc, filePath, lineno, ok:= runtime.Caller(skip)
-                if !ok {
-                    break
-                }
-
-                if strings.HasSuffix(filePath, ".s") {
-                    continue
-                }
-
-                p := runtime.FuncForPC(pc)
-                if p == nil {
-                    break
-                }
-
-                funcName := p.Name()
-                if strings.HasSuffix(funcName, "go-runtime/pkg/runtime/fnhandler.NewHandler.func1") {
-                    break
-                }
-                if !strings.HasPrefix(funcName, "runtime.") && !strings.HasPrefix(funcName, "reflect.") {
-                    filePath = hideAbsolutePath(filePath)
-                    funcName = formatFuncName(funcName)
-                    panicBuffer.WriteString(fmt.Sprintf("%s()\n    %s:%d\n", funcName, filePath, lineno))
-                    stackTrace = append(stackTrace, &stack{FunctionName: funcName, File: filePath, Lineno: lineno})
-                    stackCount += 1
-                    if stackCount >= maxFunctionInvokeStackDepth {
-                        break
-                    }
-                }
-            }
-            
-            if invokeType != invokeTypeAsync {
-                invokeErr.ErrorMsg = makePanicMessage(fmt.Sprintf("%+v", e), stackTrace)
-            }
-            if len(panicBuffer.String()) > 0 {
-                fmt.Print(panicBuffer.String())
-            }
-            funcErr = invokeErr
-        }
+    if err := fn.initialize(req.Header); err != nil {
+        return err
+    }
+
+    // Created after the initializer so that its run time is not charged
+    // against the timeout of the invocation that triggered it.
+    contextHTTPHeaderObj := rtcontext.GetContextHTTPHeadInstance(req)
+    contextProvider := rtcontext.GetContextProvider(contextObj, contextHTTPHeaderObj)
+
+    var ctx context.Context
+    var cancel context.CancelFunc
+    if deadline := contextProvider.GetDeadline(); !deadline.IsZero() {
+        ctx, cancel = context.WithDeadline(parent, deadline)
+    } else {
+        ctx, cancel = context.WithCancel(parent)
+    }
+    defer cancel()
+    contextProvider = contextProvider.WithContext(ctx).WithInFlight(fn.InFlightInvocations)
+
+    if err := fn.acquireSlot(ctx); err != nil {
+        contextProvider.GetLogger().Warnf("%s", err.ErrorMsg)
+        return err
+    }
+
+    done := make(chan handleResult, 1)
+    go func() {
+        defer fn.releaseSlot()
+        result, err := fn.handler.Handle(payload, contextProvider)
+        done <- handleResult{result: result, err: err}
     }()
-    
+
+    var invokeResult interface{}
+    var err error
+    select {
+    case result := <-done:
+        invokeResult, err = result.result, result.err
+    case <-ctx.Done():
+        errorMessage := fmt.Sprintf("Function execution exceeded the timeout of %d seconds.", contextProvider.GetRunningTimeInSeconds())
+        contextProvider.GetLogger().Errorf("%s", errorMessage)
+        return newInvokeError(http.StatusGatewayTimeout, errorTypeFunctionTimeout, errorMessage, true)
+    }
+
     var invokeErr error
-    invokeResult, err := fn.handler.Handle(payload, contextProvider)
+    var handlerErr *InvokeError
+    if errors.As(err, &handlerErr) {
+        contextProvider.GetLogger().Errorf("%s", handlerErr.ErrorMsg)
+        return handlerErr
+    }
+    var userErr *UserError
+    if errors.As(err, &userErr) {
+        errorMessage := hideAbsolutePath(userErr.Message)
+        if invokeType == invokeTypeAsync && !userErr.Retryable {
+            contextProvider.GetLogger().Warnf("acknowledging async invocation failed with non-retryable %s: %s", userErr.Type, errorMessage)
+            resp.StatusCode = http.StatusOK
+            resp.Payload = EmptyStringBytes
+            return nil
+        }
+        contextProvider.GetLogger().Errorf("%s: %s", userErr.Type, errorMessage)
+        return userErr.toInvokeError()
+    }
     if err != nil {
         errorMessage := hideAbsolutePath(err.Error())
-        invokeErr = &InvokeError{
-            ErrorCode: http.StatusInternalServerError,
-            ErrorMsg:  makeErrorMessage(errorMessage, "FunctionReturnError", nil),
-        }
-        contextProvider.GetLogger().Logf("%s", errorMessage)
+        invokeErr = newInvokeError(http.StatusInternalServerError, "FunctionReturnError", errorMessage, true)
+        contextProvider.GetLogger().Errorf("%s", errorMessage)
         return invokeErr
     }
 
@@ -188,22 +267,18 @@ func (fn *Function) Invoke(req *common.InvokeRequest, resp *common.InvokeRespons
         resp.Payload = EmptyStringBytes
         return nil
     }
+    // Size limit and spilling were applied by writeResponse.
     finalResult, _ := transformInvokeResultToBytes(invokeResult)
-
-    if maxResponseBodySize > 0 && len(finalResult) > maxResponseBodySize {
-        errorMessage := fmt.Sprintf("Response body size '%d' larger than max value '%d'.", len(finalResult), maxResponseBodySize)
-        invokeErr =  &InvokeError{
-            ErrorCode: http.StatusInsufficientStorage,
-            ErrorMsg:  makeErrorMessage(errorMessage, "FunctionResponseTooLarge", nil),
-        }
-        contextProvider.GetLogger().Logf("%s", errorMessage)
-        return invokeErr
-    }
     resp.StatusCode = http.StatusOK
     resp.Payload = finalResult
     return nil
 }
 
+type handleResult struct {
+    result interface{}
+    err    error
+}
+
 func (fn *Function) HealthCheck(req *common.HealthCheckRequest, resp *common.HealthCheckResponse) error {
     *resp = common.HealthCheckResponse{}
     return nil
@@ -0,0 +1,58 @@
+package fnhandler
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "testing"
+    "time"
+
+    "huaweicloud.com/go-runtime/pkg/runtime/common"
+)
+
+// invokeFunction invokes fn like the host does, with a sync invocation
+// unless header sets X-CFF-Invoke-Type.
+func invokeFunction(fn *Function, payload []byte, header http.Header) (*common.InvokeResponse, error) {
+    if header == nil {
+        header = http.Header{}
+    }
+    if header.Get(headerCFFInvokeType) == "" {
+        header.Set(headerCFFInvokeType, invokeTypeSync)
+    }
+    resp := &common.InvokeResponse{}
+    if err := fn.Invoke(&common.InvokeRequest{Payload: payload, Header: header}, resp); err != nil {
+        return nil, err
+    }
+    return resp, nil
+}
+
+func TestInvokeTimeout(t *testing.T) {
+    handler, started, release := blockingHandler()
+    fn := NewFunction(handler, WithMaxConcurrency(1, 0, 10*time.Millisecond))
+
+    ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+    defer cancel()
+    req := &common.InvokeRequest{Payload: []byte("{}"), Header: http.Header{}}
+    err := fn.invoke(ctx, req, &common.InvokeResponse{})
+    var invokeErr *InvokeError
+    if !errors.As(err, &invokeErr) || invokeErr.ErrorCode != http.StatusGatewayTimeout || invokeErr.ErrorType != errorTypeFunctionTimeout {
+        t.Fatalf("err = %v, want a 504 %s", err, errorTypeFunctionTimeout)
+    }
+    <-started
+
+    // The handler ignored ctx and still runs, so it keeps its slot.
+    if inflight, running, _ := fn.counts(); inflight != 0 || running != 1 {
+        t.Errorf("inflight = %d, running = %d after the timeout, want 0 and 1", inflight, running)
+    }
+    _, err = invokeFunction(fn, []byte("{}"), nil)
+    requireTooManyInvocations(t, err, "timed out waiting for a free slot")
+
+    close(release)
+    waitFor(t, "the timed-out handler to release its slot", func() bool {
+        _, running, _ := fn.counts()
+        return running == 0
+    })
+    if _, err := invokeFunction(fn, []byte("{}"), nil); err != nil {
+        t.Fatalf("invocation after the slot was released: %s", err)
+    }
+}
@@ -0,0 +1,172 @@
+package fnhandler
+
+import (
+    "bytes"
+    "fmt"
+    "net/http"
+    "runtime"
+    "strings"
+    "time"
+
+    "huaweicloud.com/go-runtime/go-api/context"
+)
+
+// Middleware wraps a handler to add behaviour around every invocation.
+type Middleware func(IRequestHandler) IRequestHandler
+
+// Chain wraps handler with middlewares, the first middleware being the
+// outermost one.
+func Chain(handler IRequestHandler, middlewares ...Middleware) IRequestHandler {
+    for i := len(middlewares) - 1; i >= 0; i-- {
+        handler = middlewares[i](handler)
+    }
+    return handler
+}
+
+// WithMiddleware adds middlewares around the function handler.
+func WithMiddleware(middlewares ...Middleware) FunctionOption {
+    return func(fn *Function) {
+        fn.middlewares = append(fn.middlewares, middlewares...)
+    }
+}
+
+// RequestLogging logs the payload at debug level and the outcome and latency
+// of every invocation.
+func RequestLogging() Middleware {
+    return func(next IRequestHandler) IRequestHandler {
+        return HandlerFunc(func(payload []byte, ctx context.RuntimeContext) (interface{}, error) {
+            logger := ctx.GetLogger()
+            logger.Debugf("invoke payload: %s", payload)
+
+            startTime := time.Now()
+            result, err := next.Handle(payload, ctx)
+            latency := time.Since(startTime)
+            if err != nil {
+                logger.Errorf("invoke failed in %s: %s", latency, hideAbsolutePath(err.Error()))
+            } else {
+                logger.Infof("invoke succeeded in %s", latency)
+            }
+            return result, err
+        })
+    }
+}
+
+// PanicReport describes a panic recovered from a handler.
+type PanicReport struct {
+    Recovered  interface{}
+    StackTrace []string
+}
+
+// PanicReporter receives every recovered panic, e.g. to forward it to an
+// error tracker.
+type PanicReporter func(ctx context.RuntimeContext, report *PanicReport)
+
+// PanicRecovery converts a handler panic into a 555 InvokeError carrying the
+// stack trace, and passes it to reporters.
+func PanicRecovery(reporters ...PanicReporter) Middleware {
+    return func(next IRequestHandler) IRequestHandler {
+        return HandlerFunc(func(payload []byte, ctx context.RuntimeContext) (invokeResult interface{}, funcErr error) {
+            defer func() {
+                if e := recover(); e != nil {
+                    invokeErr, report := newPanicError(e, ctx, ctx.GetInvokeType())
+                    for _, reporter := range reporters {
+                        reporter(ctx, report)
+                    }
+                    invokeResult = nil
+                    funcErr = invokeErr
+                }
+            }()
+
+            return next.Handle(payload, ctx)
+        })
+    }
+}
+
+// newPanicError must be called from the deferred function that recovered e,
+// it walks the stack of the panicking goroutine up to the handler boundary.
+func newPanicError(e interface{}, ctx context.RuntimeContext, invokeType string) (*InvokeError, *PanicReport) {
+    ctx.GetLogger().Errorf("invoke function  failed for function code panic, error=%+v.", e)
+    var panicBuffer bytes.Buffer
+
+    invokeErr := &InvokeError{
+        ErrorCode: 555,
+        ErrorType: errorTypePanic,
+    }
+    stackTrace := make([]*stack, 0)
+    stackCount := 0
+    // skip this function and the deferred function that called it
+    for skip := 2; ; skip++ {
+        pc, filePath, lineno, ok:= runtime.Caller(skip)
+        if !ok {
+            break
+        }
+
+        if strings.HasSuffix(filePath, ".s") {
+            continue
+        }
+
+        p := runtime.FuncForPC(pc)
+        if p == nil {
+            break
+        }
+
+        funcName := p.Name()
+        if isHandlerBoundary(funcName) {
+            break
+        }
+        if !strings.HasPrefix(funcName, "runtime.") && !strings.HasPrefix(funcName, "reflect.") {
+            filePath = hideAbsolutePath(filePath)
+            funcName = formatFuncName(funcName)
+            panicBuffer.WriteString(fmt.Sprintf("%s()\n    %s:%d\n", funcName, filePath, lineno))
+            stackTrace = append(stackTrace, &stack{FunctionName: funcName, File: filePath, Lineno: lineno})
+            stackCount += 1
+            if stackCount >= maxFunctionInvokeStackDepth {
+                break
+            }
+        }
+    }
+
+    if invokeType != invokeTypeAsync {
+        invokeErr.ErrorMsg = makePanicMessage(fmt.Sprintf("%+v", e), stackTrace)
+    }
+    if len(panicBuffer.String()) > 0 {
+        fmt.Print(panicBuffer.String())
+    }
+
+    report := &PanicReport{Recovered: e}
+    for _, frame := range stackTrace {
+        report.StackTrace = append(report.StackTrace, fmt.Sprintf("%s()\n    %s:%d", frame.FunctionName, frame.File, frame.Lineno))
+    }
+    return invokeErr, report
+}
+
+// PayloadLimit rejects payloads larger than maxBytes with a 413 InvokeError.
+func PayloadLimit(maxBytes int) Middleware {
+    return func(next IRequestHandler) IRequestHandler {
+        return HandlerFunc(func(payload []byte, ctx context.RuntimeContext) (interface{}, error) {
+            if maxBytes > 0 && len(payload) > maxBytes {
+                errorMessage := fmt.Sprintf("Request payload size '%d' larger than max value '%d'.", len(payload), maxBytes)
+                return nil, newInvokeError(http.StatusRequestEntityTooLarge, "FunctionPayloadTooLarge", errorMessage, false)
+            }
+            return next.Handle(payload, ctx)
+        })
+    }
+}
+
+// MetricsRecorder receives the outcome of every invocation.
+type MetricsRecorder interface {
+    ObserveInvocation(ctx context.RuntimeContext, payloadSize int, duration time.Duration, err error)
+}
+
+// InvocationMetrics reports the payload size, duration and error of every
+// invocation to recorder.
+func InvocationMetrics(recorder MetricsRecorder) Middleware {
+    return func(next IRequestHandler) IRequestHandler {
+        return HandlerFunc(func(payload []byte, ctx context.RuntimeContext) (interface{}, error) {
+            startTime := time.Now()
+            result, err := next.Handle(payload, ctx)
+            recorder.ObserveInvocation(ctx, len(payload), time.Since(startTime), err)
+            return result, err
+        })
+    }
+}
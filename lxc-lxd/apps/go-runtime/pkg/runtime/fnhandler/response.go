@@ -0,0 +1,322 @@
+package fnhandler
+
+import (
+    "bytes"
+    stdcontext "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "path/filepath"
+    "time"
+
+    "huaweicloud.com/go-runtime/go-api/context"
+)
+
+const (
+    errorTypeResponseTooLarge = "FunctionResponseTooLarge"
+    errorTypeResponseStore    = "FunctionResponseStoreError"
+
+    // defaultResponseSpillTTL is how long the DirResponseStore configured by
+    // RUNTIME_RESPONSE_SPILL_DIR keeps responses when RUNTIME_RESPONSE_SPILL_TTL
+    // (in seconds) is not set.
+    defaultResponseSpillTTL = time.Hour
+)
+
+// StreamFunc can be returned by a handler instead of a value to write a large
+// response body piece by piece. A handler may also return an io.Reader, which
+// is read to the end and closed if it is an io.Closer. Either way the body is
+// checked against RUNTIME_MAX_RESP_BODY_SIZE while it is written, so an
+// oversized response fails without being held in memory, or is moved to the
+// ResponseStore when one is configured. Async invocations have no response
+// body, their StreamFunc still runs but its output is discarded.
+type StreamFunc func(w io.Writer) error
+
+// ResponseStore receives response bodies larger than
+// RUNTIME_MAX_RESP_BODY_SIZE. The caller then gets a ResponseReference
+// instead of the body.
+type ResponseStore interface {
+    // Create starts storing the response of the invocation with the given
+    // request ID, which may be empty.
+    Create(ctx stdcontext.Context, requestID string) (ResponseObject, error)
+}
+
+// ResponseObject is a response body being written to a ResponseStore.
+type ResponseObject interface {
+    io.Writer
+    // Commit makes the body available and returns where it can be fetched.
+    Commit() (location string, err error)
+    // Abort discards the body after a failed write.
+    Abort() error
+}
+
+// ResponseReference is the response payload of an invocation whose body was
+// moved to the ResponseStore.
+type ResponseReference struct {
+    Location string `json:"location"`
+    Size     int64  `json:"size"`
+}
+
+// WithResponseStore moves response bodies larger than
+// RUNTIME_MAX_RESP_BODY_SIZE to store instead of failing the invocation. It
+// replaces the DirResponseStore configured by RUNTIME_RESPONSE_SPILL_DIR.
+func WithResponseStore(store ResponseStore) FunctionOption {
+    return func(fn *Function) {
+        fn.responseStore = store
+    }
+}
+
+// DirResponseStore stores response bodies as files in Dir, standing in for an
+// object storage bucket mounted or synced at that path. Files are named after
+// the request ID, or get a random name when it is empty.
+//
+// With a positive TTL the store deletes the files older than TTL, including
+// those of interrupted writes, whenever it stores a response; callers must
+// fetch a response within TTL. Without TTL nothing is deleted and whoever
+// syncs Dir owns the cleanup.
+type DirResponseStore struct {
+    Dir string
+    TTL time.Duration
+}
+
+func (s DirResponseStore) Create(ctx stdcontext.Context, requestID string) (ResponseObject, error) {
+    if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+        return nil, err
+    }
+    if s.TTL > 0 {
+        s.removeExpired()
+    }
+    name := filepath.Base(requestID)
+    if requestID == "" || name == "." || name == ".." || name == string(filepath.Separator) {
+        var err error
+        if name, err = randomObjectName(); err != nil {
+            return nil, err
+        }
+    }
+    file, err := os.CreateTemp(s.Dir, "."+name+".*")
+    if err != nil {
+        return nil, err
+    }
+    return &fileObject{file: file, path: filepath.Join(s.Dir, name)}, nil
+}
+
+// removeExpired deletes the files in Dir last modified more than TTL ago.
+// Failures only delay the cleanup to the next response.
+func (s DirResponseStore) removeExpired() {
+    entries, err := os.ReadDir(s.Dir)
+    if err != nil {
+        return
+    }
+    expired := time.Now().Add(-s.TTL)
+    for _, entry := range entries {
+        if !entry.Type().IsRegular() {
+            continue
+        }
+        info, err := entry.Info()
+        if err != nil || info.ModTime().After(expired) {
+            continue
+        }
+        os.Remove(filepath.Join(s.Dir, entry.Name()))
+    }
+}
+
+// randomObjectName names the response of an invocation without request ID.
+func randomObjectName() (string, error) {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return "response-" + hex.EncodeToString(b), nil
+}
+
+type fileObject struct {
+    file *os.File
+    path string
+}
+
+func (o *fileObject) Write(p []byte) (int, error) {
+    return o.file.Write(p)
+}
+
+func (o *fileObject) Commit() (string, error) {
+    if err := o.file.Chmod(0o644); err != nil {
+        o.Abort()
+        return "", err
+    }
+    if err := o.file.Close(); err != nil {
+        os.Remove(o.file.Name())
+        return "", err
+    }
+    if err := os.Rename(o.file.Name(), o.path); err != nil {
+        os.Remove(o.file.Name())
+        return "", err
+    }
+    path, err := filepath.Abs(o.path)
+    if err != nil {
+        return "", err
+    }
+    return "file://" + filepath.ToSlash(path), nil
+}
+
+func (o *fileObject) Abort() error {
+    o.file.Close()
+    return os.Remove(o.file.Name())
+}
+
+var (
+    errResponseTooLarge = errors.New("response body too large")
+    errResponseStore    = errors.New("store response failed")
+)
+
+// responseWriter keeps the response body in memory up to limit and moves it
+// to store once it grows beyond. The first write error is kept, so a
+// StreamFunc ignoring it cannot return a truncated body.
+type responseWriter struct {
+    ctx       stdcontext.Context
+    requestID string
+    limit     int
+    store     ResponseStore
+
+    buf    bytes.Buffer
+    object ResponseObject
+    size   int64
+    err    error
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+    if w.err != nil {
+        return 0, w.err
+    }
+    n, err := w.write(p)
+    w.size += int64(n)
+    w.err = err
+    return n, err
+}
+
+func (w *responseWriter) write(p []byte) (int, error) {
+    if err := w.ctx.Err(); err != nil {
+        return 0, err
+    }
+    if w.object == nil && w.limit > 0 && w.buf.Len()+len(p) > w.limit {
+        if w.store == nil {
+            return 0, errResponseTooLarge
+        }
+        object, err := w.store.Create(w.ctx, w.requestID)
+        if err != nil {
+            return 0, fmt.Errorf("%w: %w", errResponseStore, err)
+        }
+        w.object = object
+        if _, err := object.Write(w.buf.Bytes()); err != nil {
+            return 0, fmt.Errorf("%w: %w", errResponseStore, err)
+        }
+        w.buf.Reset()
+    }
+    if w.object != nil {
+        n, err := w.object.Write(p)
+        if err != nil {
+            return n, fmt.Errorf("%w: %w", errResponseStore, err)
+        }
+        return n, nil
+    }
+    return w.buf.Write(p)
+}
+
+func (w *responseWriter) finish() ([]byte, error) {
+    if w.err != nil {
+        return nil, w.err
+    }
+    if w.object == nil {
+        return w.buf.Bytes(), nil
+    }
+    location, err := w.object.Commit()
+    if err != nil {
+        return nil, fmt.Errorf("%w: %w", errResponseStore, err)
+    }
+    return json.Marshal(&ResponseReference{Location: location, Size: w.size})
+}
+
+func (w *responseWriter) abort() {
+    if w.object != nil {
+        w.object.Abort()
+    }
+}
+
+// discardResponse runs the StreamFunc or reads the io.Reader returned by the
+// handler of an async invocation, so its work is done and its errors are
+// reported, and throws the body away.
+func discardResponse(result interface{}) (interface{}, error) {
+    var err error
+    switch v := result.(type) {
+    case StreamFunc:
+        err = v(io.Discard)
+    case func(io.Writer) error:
+        err = v(io.Discard)
+    case io.Reader:
+        _, err = io.Copy(io.Discard, v)
+        if closer, ok := v.(io.Closer); ok {
+            closer.Close()
+        }
+    default:
+        return result, nil
+    }
+    return nil, err
+}
+
+// writeResponse turns the result of the handler into the response body of a
+// sync invocation, see StreamFunc. Async results are discarded.
+func (fn *Function) writeResponse(next IRequestHandler) IRequestHandler {
+    return HandlerFunc(func(payload []byte, ctx context.RuntimeContext) (interface{}, error) {
+        result, err := next.Handle(payload, ctx)
+        if err != nil {
+            if closer, ok := result.(io.Closer); ok {
+                closer.Close()
+            }
+            return result, err
+        }
+        if ctx.GetInvokeType() == invokeTypeAsync {
+            return discardResponse(result)
+        }
+
+        w := &responseWriter{
+            ctx:       ctx.GetContext(),
+            requestID: ctx.GetRequestID(),
+            limit:     maxResponseBodySize,
+            store:     fn.responseStore,
+        }
+        switch v := result.(type) {
+        case StreamFunc:
+            err = v(w)
+        case func(io.Writer) error:
+            err = v(w)
+        case io.Reader:
+            _, err = io.Copy(w, v)
+            if closer, ok := v.(io.Closer); ok {
+                closer.Close()
+            }
+        default:
+            data, _ := transformInvokeResultToBytes(v)
+            _, err = w.Write(data)
+        }
+
+        var body []byte
+        if err == nil {
+            body, err = w.finish()
+        }
+        if err != nil {
+            w.abort()
+            if errors.Is(err, errResponseTooLarge) {
+                errorMessage := fmt.Sprintf("Response body size larger than max value '%d'.", maxResponseBodySize)
+                return nil, newInvokeError(http.StatusInsufficientStorage, errorTypeResponseTooLarge, errorMessage, false)
+            }
+            if errors.Is(err, errResponseStore) {
+                return nil, newInvokeError(http.StatusBadGateway, errorTypeResponseStore, hideAbsolutePath(err.Error()), true)
+            }
+            return nil, err
+        }
+        return body, nil
+    })
+}
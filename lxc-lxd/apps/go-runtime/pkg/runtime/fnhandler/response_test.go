@@ -0,0 +1,137 @@
+package fnhandler
+
+import (
+    stdcontext "context"
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "huaweicloud.com/go-runtime/go-api/context"
+)
+
+// setMaxResponseBodySize overrides RUNTIME_MAX_RESP_BODY_SIZE for one test.
+func setMaxResponseBodySize(t *testing.T, size int) {
+    previous := maxResponseBodySize
+    maxResponseBodySize = size
+    t.Cleanup(func() { maxResponseBodySize = previous })
+}
+
+func TestResponseSpill(t *testing.T) {
+    setMaxResponseBodySize(t, 8)
+    dir := t.TempDir()
+    body := strings.Repeat("x", 32)
+    fn := NewFunction(HandlerFunc(func(_ []byte, _ context.RuntimeContext) (interface{}, error) {
+        return StreamFunc(func(w io.Writer) error {
+            _, err := io.WriteString(w, body)
+            return err
+        }), nil
+    }), WithResponseStore(DirResponseStore{Dir: dir}))
+
+    for _, requestID := range []string{"req-1", ""} {
+        header := http.Header{}
+        header.Set("X-CFF-Request-Id", requestID)
+        resp, err := invokeFunction(fn, []byte("{}"), header)
+        if err != nil {
+            t.Fatalf("request ID %q: %s", requestID, err)
+        }
+        var ref ResponseReference
+        if err := json.Unmarshal(resp.Payload, &ref); err != nil {
+            t.Fatalf("request ID %q: payload %s is not a ResponseReference", requestID, resp.Payload)
+        }
+        path := strings.TrimPrefix(ref.Location, "file://")
+        if filepath.Dir(path) != dir || filepath.Base(path) == "." {
+            t.Errorf("request ID %q: location %s is not a file in %s", requestID, ref.Location, dir)
+        }
+        data, err := os.ReadFile(path)
+        if err != nil || string(data) != body || ref.Size != int64(len(body)) {
+            t.Errorf("request ID %q: stored %q (%v), size %d", requestID, data, err, ref.Size)
+        }
+    }
+}
+
+type failingStore struct{}
+
+func (failingStore) Create(stdcontext.Context, string) (ResponseObject, error) {
+    return nil, errors.New("bucket unavailable")
+}
+
+func TestResponseStoreError(t *testing.T) {
+    setMaxResponseBodySize(t, 8)
+    fn := NewFunction(HandlerFunc(func(_ []byte, _ context.RuntimeContext) (interface{}, error) {
+        return strings.Repeat("x", 32), nil
+    }), WithResponseStore(failingStore{}))
+
+    _, err := invokeFunction(fn, []byte("{}"), nil)
+    var invokeErr *InvokeError
+    if !errors.As(err, &invokeErr) || invokeErr.ErrorType != errorTypeResponseStore || invokeErr.ErrorCode != http.StatusBadGateway {
+        t.Fatalf("err = %v, want a 502 %s", err, errorTypeResponseStore)
+    }
+}
+
+func TestDirResponseStoreRemovesExpired(t *testing.T) {
+    dir := t.TempDir()
+    old := time.Now().Add(-2 * time.Hour)
+    for _, name := range []string{"expired", ".interrupted.123", "fresh"} {
+        path := filepath.Join(dir, name)
+        if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+            t.Fatal(err)
+        }
+        if name != "fresh" {
+            os.Chtimes(path, old, old)
+        }
+    }
+
+    object, err := DirResponseStore{Dir: dir, TTL: time.Hour}.Create(stdcontext.Background(), "new")
+    if err != nil {
+        t.Fatal(err)
+    }
+    object.Write([]byte("x"))
+    if _, err := object.Commit(); err != nil {
+        t.Fatal(err)
+    }
+
+    entries, _ := os.ReadDir(dir)
+    var names []string
+    for _, entry := range entries {
+        names = append(names, entry.Name())
+    }
+    if strings.Join(names, ",") != "fresh,new" {
+        t.Errorf("files after cleanup = %v, want [fresh new]", names)
+    }
+}
+
+func TestAsyncStreamFuncRuns(t *testing.T) {
+    header := func() http.Header {
+        header := http.Header{}
+        header.Set(headerCFFInvokeType, invokeTypeAsync)
+        return header
+    }
+
+    var written int
+    fn := NewFunction(HandlerFunc(func(payload []byte, _ context.RuntimeContext) (interface{}, error) {
+        return StreamFunc(func(w io.Writer) error {
+            n, _ := io.WriteString(w, "report")
+            written += n
+            if string(payload) == `"fail"` {
+                return errors.New("export failed")
+            }
+            return nil
+        }), nil
+    }))
+
+    if _, err := invokeFunction(fn, []byte(`"ok"`), header()); err != nil {
+        t.Fatalf("async stream: %s", err)
+    }
+    if written != len("report") {
+        t.Errorf("async StreamFunc wrote %d bytes, want it to run", written)
+    }
+    if _, err := invokeFunction(fn, []byte(`"fail"`), header()); err == nil {
+        t.Error("async StreamFunc error was dropped")
+    }
+}
@@ -0,0 +1,89 @@
+package fnhandler
+
+import (
+    "context"
+    "errors"
+    "sync/atomic"
+    "time"
+
+    "huaweicloud.com/go-runtime/pkg/runtime/common"
+    "huaweicloud.com/go-runtime/pkg/runtime/metrics"
+    "huaweicloud.com/go-runtime/pkg/runtime/tracing"
+)
+
+const (
+    metricInvocations  = "fg_runtime_invocations_total"
+    metricDuration     = "fg_runtime_invocation_duration_seconds"
+    metricResponseSize = "fg_runtime_response_size_bytes"
+    metricInFlight     = "fg_runtime_invocations_in_flight"
+    metricQueued       = "fg_runtime_invocations_queued"
+
+    outcomeSuccess = "success"
+    outcomeError   = "error"
+    outcomeTimeout = "timeout"
+    outcomePanic   = "panic"
+)
+
+// runningInvocations and queuedInvocations add up all Functions of the
+// process, so the gauges are registered once no matter how many Functions
+// NewFunction creates.
+var runningInvocations, queuedInvocations atomic.Int64
+
+func init() {
+    metrics.Default.NewGaugeFunc(metricInFlight, "Invocations currently running.", func() float64 {
+        return float64(runningInvocations.Load())
+    })
+    metrics.Default.NewGaugeFunc(metricQueued, "Invocations waiting for a concurrency slot.", func() float64 {
+        return float64(queuedInvocations.Load())
+    })
+    metrics.Default.NewCounter(metricInvocations, "Invocations by invoke type, outcome and error type.")
+    metrics.Default.NewHistogram(metricDuration, "Invocation duration in seconds.", metrics.DefaultBuckets)
+    metrics.Default.NewHistogram(metricResponseSize, "Size of successful responses in bytes.",
+        []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576, 6291456})
+}
+
+// Invoke runs one invocation of the handler; it is the method the net/rpc
+// host calls. Every invocation is counted in the metrics.Default registry
+// and traced with a span whose context reaches the handler through
+// RuntimeContext.Context().
+func (fn *Function) Invoke(req *common.InvokeRequest, resp *common.InvokeResponse) error {
+    start := time.Now()
+    ctx, span := tracing.StartInvocation(context.Background(), req.Header)
+    err := fn.invoke(ctx, req, resp)
+
+    invokeType := req.Header.Get(headerCFFInvokeType)
+    if invokeType == "" {
+        invokeType = invokeTypeSync
+    }
+    outcome, errorType := invocationOutcome(err)
+    metrics.Default.AddCounter(metricInvocations, 1, "invoke_type", invokeType, "outcome", outcome, "error_type", errorType)
+    metrics.Default.ObserveHistogram(metricDuration, time.Since(start).Seconds(), "invoke_type", invokeType, "outcome", outcome)
+    tracing.EndInvocation(span, outcome, errorType, err)
+    if err == nil {
+        metrics.Default.ObserveHistogram(metricResponseSize, float64(len(resp.Payload)), "invoke_type", invokeType)
+    }
+    return err
+}
+
+func invocationOutcome(err error) (outcome, errorType string) {
+    if err == nil {
+        return outcomeSuccess, ""
+    }
+    var invokeErr *InvokeError
+    if !errors.As(err, &invokeErr) {
+        return outcomeError, "unknown"
+    }
+    switch invokeErr.ErrorType {
+    case errorTypePanic:
+        return outcomePanic, invokeErr.ErrorType
+    case errorTypeFunctionTimeout:
+        return outcomeTimeout, invokeErr.ErrorType
+    }
+    return outcomeError, invokeErr.ErrorType
+}
+
+func (fn *Function) inflightCount() float64 {
+    fn.inflightMu.Lock()
+    defer fn.inflightMu.Unlock()
+    return float64(fn.running)
+}
@@ -0,0 +1,102 @@
+package fnhandler
+
+import (
+    stdcontext "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "reflect"
+
+    "huaweicloud.com/go-runtime/go-api/context"
+)
+
+const (
+    ErrorTypeEventDecode     = "EventDecodeError"
+    ErrorTypeEventValidation = "EventValidationError"
+)
+
+// Validator is implemented by event types that can check their own content
+// once decoded. Validation failures are returned to the caller as a 400
+// InvokeError with ErrorTypeEventValidation.
+type Validator interface {
+    Validate() error
+}
+
+// EventDecodeError describes why a payload could not be decoded into the
+// handler's event type.
+type EventDecodeError struct {
+    Event  string `json:"event"`
+    Field  string `json:"field,omitempty"`
+    Offset int64  `json:"offset,omitempty"`
+    Reason string `json:"reason"`
+}
+
+func newEventDecodeError(event string, err error) *EventDecodeError {
+    decodeErr := &EventDecodeError{Event: event, Reason: err.Error()}
+    var syntaxErr *json.SyntaxError
+    var typeErr *json.UnmarshalTypeError
+    if errors.As(err, &syntaxErr) {
+        decodeErr.Offset = syntaxErr.Offset
+    } else if errors.As(err, &typeErr) {
+        decodeErr.Field = typeErr.Field
+        decodeErr.Offset = typeErr.Offset
+    }
+    return decodeErr
+}
+
+func eventDecodeInvokeError(eventName string, decodeErr *EventDecodeError) *InvokeError {
+    return &InvokeError{
+        ErrorCode: http.StatusBadRequest,
+        ErrorType: ErrorTypeEventDecode,
+        ErrorMsg:  makeErrorMessageWithDetails(fmt.Sprintf("decode payload into %s failed", eventName), ErrorTypeEventDecode, nil, decodeErr),
+    }
+}
+
+// TypedHandlerFunc is a handler that receives its payload decoded into E.
+type TypedHandlerFunc[E, R any] func(stdcontext.Context, E, context.RuntimeContext) (R, error)
+
+// NewTypedHandler wraps fn so the payload is JSON decoded into E, and
+// validated when E implements Validator, before fn is called.
+func NewTypedHandler[E, R any](fn TypedHandlerFunc[E, R]) IRequestHandler {
+    if fn == nil {
+        return &FaultRequestHandler{err: &FunctionLoadFailedError{
+            StatusCode:   http.StatusBadRequest,
+            ErrorMessage: "handler is nil",
+        }}
+    }
+
+    return &RequestHandler{handlerFunc: HandlerFunc(func(payload []byte, ctx context.RuntimeContext) (interface{}, error) {
+        event, err := decodeEvent[E](payload)
+        if err != nil {
+            return nil, err
+        }
+        return fn(ctx.GetContext(), event, ctx)
+    })}
+}
+
+func decodeEvent[E any](payload []byte) (E, error) {
+    var event E
+    eventName := fmt.Sprintf("%T", event)
+    if err := json.Unmarshal(payload, &event); err != nil {
+        return event, eventDecodeInvokeError(eventName, newEventDecodeError(eventName, err))
+    }
+    // A null payload leaves a pointer event nil, which Validate and the
+    // handler cannot use.
+    if rv := reflect.ValueOf(&event).Elem(); rv.Kind() == reflect.Pointer && rv.IsNil() {
+        return event, eventDecodeInvokeError(eventName, &EventDecodeError{Event: eventName, Reason: "payload is null"})
+    }
+
+    var validator Validator
+    if v, ok := interface{}(&event).(Validator); ok {
+        validator = v
+    } else if v, ok := interface{}(event).(Validator); ok {
+        validator = v
+    }
+    if validator != nil {
+        if err := validator.Validate(); err != nil {
+            return event, newInvokeError(http.StatusBadRequest, ErrorTypeEventValidation, fmt.Sprintf("invalid %s: %s", eventName, hideAbsolutePath(err.Error())), false)
+        }
+    }
+    return event, nil
+}
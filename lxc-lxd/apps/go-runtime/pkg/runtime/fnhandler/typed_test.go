@@ -0,0 +1,98 @@
+package fnhandler
+
+import (
+    stdcontext "context"
+    "errors"
+    "net/http"
+    "testing"
+
+    "huaweicloud.com/go-runtime/go-api/context"
+)
+
+type testEvent struct {
+    Name string `json:"name"`
+}
+
+func (e *testEvent) Validate() error {
+    if e.Name == "" {
+        return errors.New("name is required")
+    }
+    return nil
+}
+
+func TestDecodeEvent(t *testing.T) {
+    tests := []struct {
+        name     string
+        payload  string
+        decode   func([]byte) error
+        wantCode int
+        wantType string
+    }{
+        {
+            name:    "valid",
+            payload: `{"name":"demo"}`,
+            decode:  func(p []byte) error { _, err := decodeEvent[testEvent](p); return err },
+        },
+        {
+            name:     "syntax error",
+            payload:  `{"name":`,
+            decode:   func(p []byte) error { _, err := decodeEvent[testEvent](p); return err },
+            wantCode: http.StatusBadRequest,
+            wantType: ErrorTypeEventDecode,
+        },
+        {
+            name:     "validation error",
+            payload:  `{}`,
+            decode:   func(p []byte) error { _, err := decodeEvent[testEvent](p); return err },
+            wantCode: http.StatusBadRequest,
+            wantType: ErrorTypeEventValidation,
+        },
+        {
+            name:     "null into pointer",
+            payload:  `null`,
+            decode:   func(p []byte) error { _, err := decodeEvent[*testEvent](p); return err },
+            wantCode: http.StatusBadRequest,
+            wantType: ErrorTypeEventDecode,
+        },
+        {
+            name:    "pointer",
+            payload: `{"name":"demo"}`,
+            decode:  func(p []byte) error { _, err := decodeEvent[*testEvent](p); return err },
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := tt.decode([]byte(tt.payload))
+            if tt.wantType == "" {
+                if err != nil {
+                    t.Fatalf("decode %s: %s", tt.payload, err)
+                }
+                return
+            }
+            var invokeErr *InvokeError
+            if !errors.As(err, &invokeErr) {
+                t.Fatalf("decode %s: err = %v, want an InvokeError", tt.payload, err)
+            }
+            if invokeErr.ErrorCode != tt.wantCode || invokeErr.ErrorType != tt.wantType {
+                t.Errorf("decode %s: got %d %s, want %d %s", tt.payload, invokeErr.ErrorCode, invokeErr.ErrorType, tt.wantCode, tt.wantType)
+            }
+        })
+    }
+}
+
+func TestTypedHandlerNullPayload(t *testing.T) {
+    called := false
+    fn := NewFunction(NewTypedHandler(func(_ stdcontext.Context, event *testEvent, _ context.RuntimeContext) (string, error) {
+        called = true
+        return event.Name, nil
+    }))
+
+    _, err := invokeFunction(fn, []byte("null"), nil)
+    var invokeErr *InvokeError
+    if !errors.As(err, &invokeErr) || invokeErr.ErrorCode != http.StatusBadRequest || invokeErr.ErrorType != ErrorTypeEventDecode {
+        t.Fatalf("invoke with null payload: err = %v, want a 400 %s", err, ErrorTypeEventDecode)
+    }
+    if called {
+        t.Error("handler was called with a nil event")
+    }
+}
@@ -0,0 +1,123 @@
+// Package httptransport exposes a fnhandler.Function over plain HTTP/JSON so
+// it can be called with curl or run as an ordinary container:
+//
+//     POST /invoke   body is the event payload, X-CFF-* headers are passed through
+//     GET  /health   returns 200 when the function is serving
+//
+// A missing X-CFF-Request-Id or X-CFF-Invoke-Type is synthesized, see
+// common.NewInvokeRequest.
+//
+// A failed invocation is answered with the InvokeError as JSON and its
+// ErrorCode as the HTTP status, error_msg being the error message object.
+package httptransport
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "log"
+    "net/http"
+
+    "huaweicloud.com/go-runtime/pkg/runtime/common"
+    "huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
+)
+
+const (
+    InvokePath = "/invoke"
+    HealthPath = "/health"
+)
+
+type handler struct {
+    function *fnhandler.Function
+}
+
+// NewHandler returns the HTTP handler serving function.
+func NewHandler(function *fnhandler.Function) http.Handler {
+    h := &handler{function: function}
+    mux := http.NewServeMux()
+    mux.HandleFunc(InvokePath, h.invoke)
+    mux.HandleFunc(HealthPath, h.health)
+    return mux
+}
+
+func (h *handler) invoke(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        w.Header().Set("Allow", http.MethodPost)
+        writeError(w, &fnhandler.InvokeError{ErrorCode: http.StatusMethodNotAllowed, ErrorMsg: "method not allowed"})
+        return
+    }
+
+    payload, err := io.ReadAll(r.Body)
+    if err != nil {
+        writeError(w, &fnhandler.InvokeError{ErrorCode: http.StatusBadRequest, ErrorMsg: "read request body failed"})
+        return
+    }
+
+    req := common.NewInvokeRequest(payload, r.Header)
+    resp := &common.InvokeResponse{}
+    if err := h.function.Invoke(req, resp); err != nil {
+        invokeErr, ok := err.(*fnhandler.InvokeError)
+        if !ok {
+            invokeErr = &fnhandler.InvokeError{ErrorCode: http.StatusInternalServerError, ErrorMsg: err.Error()}
+        }
+        writeError(w, invokeErr)
+        return
+    }
+
+    if json.Valid(resp.Payload) {
+        w.Header().Set("Content-Type", "application/json")
+    } else {
+        w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+    }
+    w.WriteHeader(resp.StatusCode)
+    if _, err := w.Write(resp.Payload); err != nil {
+        log.Printf("write invoke response failed: %s", err)
+    }
+}
+
+func (h *handler) health(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+        w.Header().Set("Allow", http.MethodGet)
+        writeError(w, &fnhandler.InvokeError{ErrorCode: http.StatusMethodNotAllowed, ErrorMsg: "method not allowed"})
+        return
+    }
+
+    resp := &common.HealthCheckResponse{}
+    if err := h.function.HealthCheck(&common.HealthCheckRequest{}, resp); err != nil {
+        writeError(w, &fnhandler.InvokeError{ErrorCode: http.StatusServiceUnavailable, ErrorMsg: err.Error()})
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusOK)
+    json.NewEncoder(w).Encode(resp)
+}
+
+// errorResponse is an InvokeError whose ErrorMsg, the JSON encoded
+// InvokeErrorMessage, is embedded as an object rather than a string.
+type errorResponse struct {
+    ErrorCode int             `json:"error_code"`
+    ErrorType string          `json:"error_type,omitempty"`
+    ErrorMsg  json.RawMessage `json:"error_msg"`
+    Retryable bool            `json:"retryable"`
+}
+
+func writeError(w http.ResponseWriter, invokeErr *fnhandler.InvokeError) {
+    statusCode := invokeErr.ErrorCode
+    if statusCode < 100 || statusCode > 999 {
+        statusCode = http.StatusInternalServerError
+    }
+    resp := errorResponse{
+        ErrorCode: invokeErr.ErrorCode,
+        ErrorType: invokeErr.ErrorType,
+        ErrorMsg:  json.RawMessage(invokeErr.ErrorMsg),
+        Retryable: invokeErr.Retryable,
+    }
+    if !bytes.HasPrefix(resp.ErrorMsg, []byte("{")) || !json.Valid(resp.ErrorMsg) {
+        resp.ErrorMsg, _ = json.Marshal(invokeErr.ErrorMsg)
+    }
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(statusCode)
+    if err := json.NewEncoder(w).Encode(resp); err != nil {
+        log.Printf("write invoke error failed: %s", err)
+    }
+}
@@ -0,0 +1,86 @@
+package httptransport
+
+import (
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "huaweicloud.com/go-runtime/go-api/context"
+    "huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
+)
+
+func TestInvokeSynthesizesHeaders(t *testing.T) {
+    fn := fnhandler.NewFunction(fnhandler.HandlerFunc(func(_ []byte, ctx context.RuntimeContext) (interface{}, error) {
+        return map[string]string{"request_id": ctx.GetRequestID(), "invoke_type": ctx.GetInvokeType()}, nil
+    }))
+    handler := NewHandler(fn)
+
+    for _, requestID := range []string{"", "given-id"} {
+        req := httptest.NewRequest(http.MethodPost, InvokePath, strings.NewReader("{}"))
+        if requestID != "" {
+            req.Header.Set("X-CFF-Request-Id", requestID)
+        }
+        rec := httptest.NewRecorder()
+        handler.ServeHTTP(rec, req)
+
+        var got map[string]string
+        if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+            t.Fatalf("decode %s: %s", rec.Body, err)
+        }
+        if got["request_id"] == "" || (requestID != "" && got["request_id"] != requestID) {
+            t.Errorf("request ID = %q, want %q or a generated one", got["request_id"], requestID)
+        }
+        if got["invoke_type"] != "sync" {
+            t.Errorf("invoke type = %q, want sync", got["invoke_type"])
+        }
+    }
+}
+
+func TestInvokeErrorBody(t *testing.T) {
+    fn := fnhandler.NewFunction(fnhandler.HandlerFunc(func(_ []byte, _ context.RuntimeContext) (interface{}, error) {
+        return nil, fnhandler.NewUserError(http.StatusBadRequest, fnhandler.ErrorTypeValidation, "subdomain is required", nil)
+    }))
+    handler := NewHandler(fn)
+
+    tests := []struct {
+        method  string
+        code    int
+        message string
+    }{
+        {method: http.MethodPost, code: http.StatusBadRequest, message: "subdomain is required"},
+        {method: http.MethodGet, code: http.StatusMethodNotAllowed},
+    }
+    for _, tt := range tests {
+        rec := httptest.NewRecorder()
+        handler.ServeHTTP(rec, httptest.NewRequest(tt.method, InvokePath, strings.NewReader("{}")))
+        body, _ := io.ReadAll(rec.Body)
+        if rec.Code != tt.code {
+            t.Errorf("%s: status = %d, want %d", tt.method, rec.Code, tt.code)
+        }
+
+        var resp struct {
+            ErrorCode int             `json:"error_code"`
+            ErrorMsg  json.RawMessage `json:"error_msg"`
+        }
+        if err := json.Unmarshal(body, &resp); err != nil {
+            t.Fatalf("%s: decode %s: %s", tt.method, body, err)
+        }
+        if tt.message == "" {
+            var message string
+            if err := json.Unmarshal(resp.ErrorMsg, &message); err != nil {
+                t.Errorf("%s: error_msg = %s, want a plain string", tt.method, resp.ErrorMsg)
+            }
+            continue
+        }
+        var message fnhandler.InvokeErrorMessage
+        if err := json.Unmarshal(resp.ErrorMsg, &message); err != nil {
+            t.Fatalf("%s: error_msg = %s, want an object: %s", tt.method, resp.ErrorMsg, err)
+        }
+        if message.ErrorMessage != tt.message || message.ErrorType != fnhandler.ErrorTypeValidation {
+            t.Errorf("%s: error_msg = %+v", tt.method, message)
+        }
+    }
+}
@@ -0,0 +1,178 @@
+package runtime
+
+import (
+    stdcontext "context"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+
+    "huaweicloud.com/go-runtime/events/smn"
+    "huaweicloud.com/go-runtime/events/timer"
+    "huaweicloud.com/go-runtime/go-api/context"
+    "huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
+)
+
+// IdempotencyState is the state of a unit of work in an IdempotencyStore.
+type IdempotencyState int
+
+const (
+    // IdempotencyFree means the work was not recorded, Begin then marks it
+    // as in progress for the caller.
+    IdempotencyFree IdempotencyState = iota
+    // IdempotencyInProgress means another run of the work has not finished,
+    // e.g. the handler of an invocation that timed out is still running.
+    IdempotencyInProgress
+    // IdempotencyDone means the work succeeded.
+    IdempotencyDone
+)
+
+// IdempotencyStore records which units of work are running or already ran.
+// Implementations backed by a shared database make Scheduled safe across
+// instances.
+type IdempotencyStore interface {
+    // Begin marks key as in progress for ttl unless it is recorded already,
+    // and returns the state it found. Only IdempotencyFree lets the caller
+    // run the work. A run that never finishes, e.g. because its instance
+    // crashed, holds key in progress until ttl expires.
+    Begin(ctx stdcontext.Context, key string, ttl time.Duration) (IdempotencyState, error)
+    // Complete marks key as done for ttl.
+    Complete(ctx stdcontext.Context, key string, ttl time.Duration) error
+    // Release forgets key so the work can run again.
+    Release(ctx stdcontext.Context, key string) error
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore local to the function
+// instance. It only catches redeliveries that reach the same instance.
+type MemoryIdempotencyStore struct {
+    mu      sync.Mutex
+    entries map[string]idempotencyEntry
+}
+
+type idempotencyEntry struct {
+    state   IdempotencyState
+    expires time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+    return &MemoryIdempotencyStore{entries: make(map[string]idempotencyEntry)}
+}
+
+func (s *MemoryIdempotencyStore) Begin(_ stdcontext.Context, key string, ttl time.Duration) (IdempotencyState, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := time.Now()
+    for k, entry := range s.entries {
+        if !now.Before(entry.expires) {
+            delete(s.entries, k)
+        }
+    }
+    if entry, ok := s.entries[key]; ok {
+        return entry.state, nil
+    }
+    s.entries[key] = idempotencyEntry{state: IdempotencyInProgress, expires: now.Add(ttl)}
+    return IdempotencyFree, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(_ stdcontext.Context, key string, ttl time.Duration) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.entries[key] = idempotencyEntry{state: IdempotencyDone, expires: time.Now().Add(ttl)}
+    return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(_ stdcontext.Context, key string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.entries, key)
+    return nil
+}
+
+// runIdempotent runs fn unless the work identified by key is done or in
+// progress in store. A key in progress fails with a retryable error, so the
+// work is retried later instead of being lost should the other run fail.
+// The key is done only once fn succeeded, and released when it fails. ran
+// reports whether fn was called.
+func runIdempotent(ctx stdcontext.Context, store IdempotencyStore, ttl time.Duration, what, key string, rtCtx context.RuntimeContext, fn func() error) (ran bool, err error) {
+    state, err := store.Begin(ctx, key, ttl)
+    if err != nil {
+        return false, fnhandler.NewUserError(http.StatusServiceUnavailable, fnhandler.ErrorTypeUpstream, fmt.Sprintf("claim %s %s: %s", what, key, err), nil)
+    }
+    switch state {
+    case IdempotencyDone:
+        rtCtx.GetLogger().Infof("%s %s already processed, skipping", what, key)
+        return false, nil
+    case IdempotencyInProgress:
+        return false, fnhandler.NewUserError(http.StatusConflict, fnhandler.ErrorTypeRetryable, fmt.Sprintf("%s %s is still being processed", what, key), nil)
+    }
+
+    // The handler may finish after the invocation timed out, so the store
+    // is updated with a context that is not canceled.
+    storeCtx := stdcontext.WithoutCancel(ctx)
+    if err := fn(); err != nil {
+        if releaseErr := store.Release(storeCtx, key); releaseErr != nil {
+            rtCtx.GetLogger().Errorf("release %s %s failed: %s", what, key, releaseErr)
+        }
+        return true, err
+    }
+    if err := store.Complete(storeCtx, key, ttl); err != nil {
+        rtCtx.GetLogger().Errorf("complete %s %s failed: %s", what, key, err)
+    }
+    return true, nil
+}
+
+// TimerRunKey identifies one scheduled run of a timer trigger by its name and
+// the time it fired for.
+func TimerRunKey(event *timer.TimerTriggerEvent) (string, error) {
+    t, err := event.ScheduledTime()
+    if err != nil {
+        return "", err
+    }
+    return event.TriggerName + "@" + t.UTC().Format(time.RFC3339), nil
+}
+
+// SMNMessageKey identifies an SMN record by its message_id, which is kept
+// when the platform redelivers the event.
+func SMNMessageKey(record smn.SMNRecord) string {
+    return record.Smn.MessageId
+}
+
+// IdempotentRecord makes the record handler fn of a batch idempotent: a
+// record whose key succeeded within ttl is skipped as succeeded, so
+// redelivering a partially failed batch only runs the records that failed.
+// A record still being processed by an earlier delivery fails with a
+// retryable error. Records with an empty key always run.
+func IdempotentRecord[T any](store IdempotencyStore, ttl time.Duration, key func(T) string, fn RecordHandlerFunc[T]) RecordHandlerFunc[T] {
+    return func(ctx stdcontext.Context, record T, rtCtx context.RuntimeContext) error {
+        recordKey := key(record)
+        if recordKey == "" {
+            return fn(ctx, record, rtCtx)
+        }
+        _, err := runIdempotent(ctx, store, ttl, "record", recordKey, rtCtx, func() error {
+            return fn(ctx, record, rtCtx)
+        })
+        return err
+    }
+}
+
+// Scheduled makes fn idempotent per timer run: a run whose TimerRunKey
+// succeeded within ttl is skipped and answered with the zero R. An
+// overlapping or redelivered run while the first one is still running fails
+// with a retryable error, so the slot still runs should the first one fail.
+func Scheduled[R any](store IdempotencyStore, ttl time.Duration, fn fnhandler.TypedHandlerFunc[timer.TimerTriggerEvent, R]) fnhandler.TypedHandlerFunc[timer.TimerTriggerEvent, R] {
+    return func(ctx stdcontext.Context, event timer.TimerTriggerEvent, rtCtx context.RuntimeContext) (R, error) {
+        var result R
+        key, err := TimerRunKey(&event)
+        if err != nil {
+            return result, fnhandler.NewUserError(http.StatusBadRequest, fnhandler.ErrorTypeValidation, err.Error(), nil)
+        }
+
+        _, err = runIdempotent(ctx, store, ttl, "timer run", key, rtCtx, func() error {
+            var fnErr error
+            result, fnErr = fn(ctx, event, rtCtx)
+            return fnErr
+        })
+        return result, err
+    }
+}
@@ -0,0 +1,147 @@
+package runtime
+
+import (
+    stdcontext "context"
+    "errors"
+    "net/http"
+    "testing"
+    "time"
+
+    "huaweicloud.com/go-runtime/events/timer"
+    "huaweicloud.com/go-runtime/go-api/context"
+    "huaweicloud.com/go-runtime/pkg/runtime/common"
+    "huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
+    "huaweicloud.com/go-runtime/pkg/runtime/context"
+)
+
+func testRuntimeContext() context.RuntimeContext {
+    header := rtcontext.GetContextHTTPHeadInstance(&common.InvokeRequest{Header: http.Header{}})
+    return rtcontext.GetContextProvider(rtcontext.GetContextEnvInstance(), header)
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+    ctx := stdcontext.Background()
+    store := NewMemoryIdempotencyStore()
+
+    steps := []struct {
+        name string
+        run  func() (IdempotencyState, error)
+        want IdempotencyState
+    }{
+        {"first begin", func() (IdempotencyState, error) { return store.Begin(ctx, "k", time.Hour) }, IdempotencyFree},
+        {"begin while running", func() (IdempotencyState, error) { return store.Begin(ctx, "k", time.Hour) }, IdempotencyInProgress},
+        {"begin after release", func() (IdempotencyState, error) {
+            store.Release(ctx, "k")
+            return store.Begin(ctx, "k", time.Hour)
+        }, IdempotencyFree},
+        {"begin after complete", func() (IdempotencyState, error) {
+            store.Complete(ctx, "k", time.Hour)
+            return store.Begin(ctx, "k", time.Hour)
+        }, IdempotencyDone},
+        {"begin after expiry", func() (IdempotencyState, error) {
+            store.Complete(ctx, "k", -time.Second)
+            return store.Begin(ctx, "k", time.Hour)
+        }, IdempotencyFree},
+    }
+    for _, step := range steps {
+        got, err := step.run()
+        if err != nil {
+            t.Fatalf("%s: %s", step.name, err)
+        }
+        if got != step.want {
+            t.Errorf("%s: state = %d, want %d", step.name, got, step.want)
+        }
+    }
+}
+
+func TestIdempotentRecordRedeliveryWhileRunning(t *testing.T) {
+    rtCtx := testRuntimeContext()
+    ctx := stdcontext.Background()
+
+    started := make(chan struct{})
+    finish := make(chan error)
+    calls := 0
+    handler := IdempotentRecord(NewMemoryIdempotencyStore(), time.Hour, func(id string) string { return id },
+        func(_ stdcontext.Context, id string, _ context.RuntimeContext) error {
+            calls++
+            if calls == 1 {
+                close(started)
+                return <-finish
+            }
+            return nil
+        })
+
+    first := make(chan error)
+    go func() { first <- handler(ctx, "m1", rtCtx) }()
+    <-started
+
+    err := handler(ctx, "m1", rtCtx)
+    var userErr *fnhandler.UserError
+    if !errors.As(err, &userErr) || !userErr.Retryable {
+        t.Fatalf("redelivery while running: err = %v, want a retryable UserError", err)
+    }
+
+    finish <- errors.New("upstream failed")
+    if err := <-first; err == nil {
+        t.Fatal("first run succeeded, want its error")
+    }
+
+    if err := handler(ctx, "m1", rtCtx); err != nil {
+        t.Fatalf("redelivery after failure: %s", err)
+    }
+    if calls != 2 {
+        t.Fatalf("record ran %d times after failed run, want 2", calls)
+    }
+
+    if err := handler(ctx, "m1", rtCtx); err != nil {
+        t.Fatalf("redelivery after success: %s", err)
+    }
+    if calls != 2 {
+        t.Errorf("record ran %d times after it succeeded, want 2", calls)
+    }
+}
+
+func TestScheduledOverlappingRun(t *testing.T) {
+    rtCtx := testRuntimeContext()
+    ctx := stdcontext.Background()
+    event := timer.TimerTriggerEvent{TriggerName: "nightly", Time: "2026-10-17T02:00:00Z"}
+
+    started := make(chan struct{})
+    finish := make(chan error)
+    calls := 0
+    run := Scheduled(NewMemoryIdempotencyStore(), time.Hour,
+        func(_ stdcontext.Context, _ timer.TimerTriggerEvent, _ context.RuntimeContext) (int, error) {
+            calls++
+            if calls == 1 {
+                close(started)
+                return 0, <-finish
+            }
+            return calls, nil
+        })
+
+    first := make(chan error)
+    go func() {
+        _, err := run(ctx, event, rtCtx)
+        first <- err
+    }()
+    <-started
+
+    if _, err := run(ctx, event, rtCtx); !fnhandler.IsRetryable(err) {
+        t.Fatalf("overlapping run: err = %v, want a retryable error", err)
+    }
+
+    finish <- errors.New("backup failed")
+    if err := <-first; err == nil {
+        t.Fatal("first run succeeded, want its error")
+    }
+
+    if got, err := run(ctx, event, rtCtx); err != nil || got != 2 {
+        t.Fatalf("retry after failure = %d, %v, want 2, nil", got, err)
+    }
+    if got, err := run(ctx, event, rtCtx); err != nil || got != 0 {
+        t.Errorf("run after success = %d, %v, want the zero result", got, err)
+    }
+    if calls != 2 {
+        t.Errorf("timer run ran %d times, want 2", calls)
+    }
+}
@@ -0,0 +1,142 @@
+// Package local drives fnhandler.Function.Invoke from the command line so a
+// function can be exercised without the FunctionGraph host.
+//
+// Any binary that calls runtime.Register can be invoked locally with:
+//
+//     ./handler local -event event.json [-invoke-type async] [-header Key=Value]
+//
+// RUNTIME_TIMEOUT, RUNTIME_MAX_RESP_BODY_SIZE, RUNTIME_RESPONSE_SPILL_DIR and
+// RUNTIME_RESPONSE_SPILL_TTL are read by the runtime exactly like in
+// production.
+package local
+
+import (
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strings"
+
+    "huaweicloud.com/go-runtime/pkg/runtime/common"
+    "huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
+)
+
+const (
+    // Command is the first argument that switches a function binary into local mode.
+    Command = "local"
+
+)
+
+// ExitError is returned by runtime.Register when a local invocation did not
+// succeed, Code is the exit code returned by Main.
+type ExitError struct {
+    Code int
+}
+
+func (e *ExitError) Error() string {
+    return fmt.Sprintf("local invocation exited with code %d", e.Code)
+}
+
+type Options struct {
+    RequestID  string
+    InvokeType string
+    Header     http.Header
+}
+
+type headerFlag http.Header
+
+func (h headerFlag) String() string {
+    return fmt.Sprintf("%v", http.Header(h))
+}
+
+func (h headerFlag) Set(value string) error {
+    parts := strings.SplitN(value, "=", 2)
+    if len(parts) != 2 || parts[0] == "" {
+        return fmt.Errorf("header %q is not in Key=Value form", value)
+    }
+    http.Header(h).Add(parts[0], parts[1])
+    return nil
+}
+
+// NewInvokeRequest builds the request the FunctionGraph host would send,
+// synthesizing the X-CFF-* headers that are not set in opts, see
+// common.NewInvokeRequest.
+func NewInvokeRequest(payload []byte, opts Options) *common.InvokeRequest {
+    header := opts.Header.Clone()
+    if header == nil {
+        header = http.Header{}
+    }
+    if opts.RequestID != "" {
+        header.Set(common.HeaderRequestID, opts.RequestID)
+    }
+    if opts.InvokeType != "" {
+        header.Set(common.HeaderInvokeType, opts.InvokeType)
+    }
+    return common.NewInvokeRequest(payload, header)
+}
+
+// Invoke runs function once, the same code path used by the net/rpc server.
+// A registered initializer runs before the handler.
+func Invoke(function *fnhandler.Function, payload []byte, opts Options) (*common.InvokeResponse, error) {
+    req := NewInvokeRequest(payload, opts)
+    resp := &common.InvokeResponse{}
+    err := function.Invoke(req, resp)
+    if err != nil {
+        return nil, err
+    }
+    return resp, nil
+}
+
+// Main parses args, invokes function with the event file and prints the
+// InvokeResponse payload to stdout or the InvokeError to stderr. It returns
+// the process exit code.
+func Main(function *fnhandler.Function, args []string, stdout, stderr io.Writer) int {
+    header := http.Header{}
+    flags := flag.NewFlagSet(Command, flag.ContinueOnError)
+    flags.SetOutput(stderr)
+    eventFile := flags.String("event", "", "path to the event JSON file, '-' reads stdin")
+    requestID := flags.String("request-id", "", "value of X-CFF-Request-Id, generated when empty")
+    invokeType := flags.String("invoke-type", "", "value of X-CFF-Invoke-Type (sync or async), defaults to sync")
+    flags.Var(headerFlag(header), "header", "extra request header in Key=Value form, may be repeated")
+    if err := flags.Parse(args); err != nil {
+        return 2
+    }
+    if *eventFile == "" {
+        fmt.Fprintln(stderr, "local: -event is required")
+        flags.Usage()
+        return 2
+    }
+
+    var payload []byte
+    var err error
+    if *eventFile == "-" {
+        payload, err = io.ReadAll(os.Stdin)
+    } else {
+        payload, err = os.ReadFile(*eventFile)
+    }
+    if err != nil {
+        fmt.Fprintf(stderr, "local: read event failed: %s\n", err)
+        return 1
+    }
+
+    resp, err := Invoke(function, payload, Options{
+        RequestID:  *requestID,
+        InvokeType: *invokeType,
+        Header:     header,
+    })
+    if err != nil {
+        if invokeErr, ok := err.(*fnhandler.InvokeError); ok {
+            data, _ := json.MarshalIndent(invokeErr, "", "    ")
+            fmt.Fprintln(stderr, string(data))
+        } else {
+            fmt.Fprintf(stderr, "local: invoke failed: %s\n", err)
+        }
+        return 1
+    }
+
+    fmt.Fprintf(stderr, "status code: %d\n", resp.StatusCode)
+    fmt.Fprintln(stdout, string(resp.Payload))
+    return 0
+}
@@ -0,0 +1,337 @@
+// Package metrics is a minimal registry of counters, gauges and histograms
+// exported in the Prometheus text format.
+//
+// Labels are passed as key, value pairs on every update, so a metric does not
+// have to declare its label names up front:
+//
+//     metrics.Default.AddCounter("orders_total", 1, "status", "paid")
+package metrics
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "math"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+const (
+    kindCounter   = "counter"
+    kindGauge     = "gauge"
+    kindHistogram = "histogram"
+)
+
+// DefaultBuckets suit durations in seconds from 5ms to 60s.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Default is the registry the runtime reports to and serves on
+// RUNTIME_METRICS_ADDR.
+var Default = NewRegistry()
+
+type Registry struct {
+    mu       sync.Mutex
+    families map[string]*family
+}
+
+type family struct {
+    name    string
+    help    string
+    kind    string
+    buckets []float64
+    series  map[string]*series
+    // fn, when set, computes the value of a gauge at collection time.
+    fn func() float64
+}
+
+type series struct {
+    labels string
+    value  float64
+    counts []uint64
+    sum    float64
+    count  uint64
+}
+
+func NewRegistry() *Registry {
+    return &Registry{families: make(map[string]*family)}
+}
+
+// NewCounter declares a counter with help text. Declaring is optional, an
+// undeclared metric is created on first update.
+func (r *Registry) NewCounter(name, help string) {
+    r.declare(name, help, kindCounter, nil)
+}
+
+func (r *Registry) NewGauge(name, help string) {
+    r.declare(name, help, kindGauge, nil)
+}
+
+// NewGaugeFunc declares a gauge whose value is fn() at collection time.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    f := r.family(name, help, kindGauge, nil)
+    if f != nil {
+        f.fn = fn
+    }
+}
+
+// NewHistogram declares a histogram with the given upper bounds, sorted
+// ascending. Undeclared histograms use DefaultBuckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) {
+    r.declare(name, help, kindHistogram, buckets)
+}
+
+func (r *Registry) declare(name, help, kind string, buckets []float64) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.family(name, help, kind, buckets)
+}
+
+// family returns the family name, creating it when needed. It returns nil
+// when name is already used by a metric of another kind.
+func (r *Registry) family(name, help, kind string, buckets []float64) *family {
+    name = sanitizeName(name)
+    f, ok := r.families[name]
+    if !ok {
+        if kind == kindHistogram && len(buckets) == 0 {
+            buckets = DefaultBuckets
+        }
+        f = &family{name: name, kind: kind, buckets: buckets, series: make(map[string]*series)}
+        r.families[name] = f
+    }
+    if f.kind != kind {
+        return nil
+    }
+    if help != "" {
+        f.help = help
+    }
+    return f
+}
+
+func (r *Registry) series(name, kind string, labels []string) *series {
+    f := r.family(name, "", kind, nil)
+    if f == nil {
+        return nil
+    }
+    key := formatLabels(labels)
+    s, ok := f.series[key]
+    if !ok {
+        s = &series{labels: key}
+        if kind == kindHistogram {
+            s.counts = make([]uint64, len(f.buckets))
+        }
+        f.series[key] = s
+    }
+    return s
+}
+
+// AddCounter adds value, which must not be negative, to the counter name.
+// Updates of a name already used by another kind of metric are dropped.
+func (r *Registry) AddCounter(name string, value float64, labels ...string) {
+    if value < 0 {
+        return
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if s := r.series(name, kindCounter, labels); s != nil {
+        s.value += value
+    }
+}
+
+// SetGauge sets the gauge name to value.
+func (r *Registry) SetGauge(name string, value float64, labels ...string) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if s := r.series(name, kindGauge, labels); s != nil {
+        s.value = value
+    }
+}
+
+// ObserveHistogram records value in the histogram name.
+func (r *Registry) ObserveHistogram(name string, value float64, labels ...string) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    s := r.series(name, kindHistogram, labels)
+    if s == nil {
+        return
+    }
+    buckets := r.families[sanitizeName(name)].buckets
+    for i, bound := range buckets {
+        if value <= bound {
+            s.counts[i]++
+        }
+    }
+    s.sum += value
+    s.count++
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format. The
+// metrics are copied under the lock and gauge functions run after it is
+// released, so they may use the registry themselves.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+    families := r.snapshot()
+
+    out := &countingWriter{w: bufio.NewWriter(w)}
+    for _, f := range families {
+        if f.help != "" {
+            fmt.Fprintf(out, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+        }
+        fmt.Fprintf(out, "# TYPE %s %s\n", f.name, f.kind)
+        if f.fn != nil {
+            fmt.Fprintf(out, "%s %s\n", f.name, formatValue(f.fn()))
+            continue
+        }
+        for _, key := range sortedSeries(f.series) {
+            f.writeSeries(out, f.series[key])
+        }
+    }
+
+    if err := out.w.Flush(); err != nil {
+        return out.n, err
+    }
+    return out.n, out.err
+}
+
+// snapshot returns a copy of the families that have a value, sorted by
+// name.
+func (r *Registry) snapshot() []*family {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    names := make([]string, 0, len(r.families))
+    for name := range r.families {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    families := make([]*family, 0, len(names))
+    for _, name := range names {
+        f := r.families[name]
+        if f.fn == nil && len(f.series) == 0 {
+            continue
+        }
+        copied := *f
+        copied.series = make(map[string]*series, len(f.series))
+        for key, s := range f.series {
+            seriesCopy := *s
+            seriesCopy.counts = append([]uint64(nil), s.counts...)
+            copied.series[key] = &seriesCopy
+        }
+        families = append(families, &copied)
+    }
+    return families
+}
+
+func (f *family) writeSeries(w io.Writer, s *series) {
+    if f.kind != kindHistogram {
+        fmt.Fprintf(w, "%s%s %s\n", f.name, s.labels, formatValue(s.value))
+        return
+    }
+    for i, bound := range f.buckets {
+        fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, withLabel(s.labels, "le", formatValue(bound)), s.counts[i])
+    }
+    fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, withLabel(s.labels, "le", "+Inf"), s.count)
+    fmt.Fprintf(w, "%s_sum%s %s\n", f.name, s.labels, formatValue(s.sum))
+    fmt.Fprintf(w, "%s_count%s %d\n", f.name, s.labels, s.count)
+}
+
+// Handler serves the registry for Prometheus to scrape.
+func (r *Registry) Handler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+        w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+        r.WriteTo(w)
+    })
+}
+
+type countingWriter struct {
+    w   *bufio.Writer
+    n   int64
+    err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+    if c.err != nil {
+        return 0, c.err
+    }
+    n, err := c.w.Write(p)
+    c.n += int64(n)
+    c.err = err
+    return n, err
+}
+
+func sortedSeries(m map[string]*series) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+// formatLabels renders key, value pairs as {k1="v1",k2="v2"} sorted by key.
+// A missing last value is taken as empty.
+func formatLabels(labels []string) string {
+    if len(labels) == 0 {
+        return ""
+    }
+    pairs := make([]string, 0, (len(labels)+1)/2)
+    for i := 0; i < len(labels); i += 2 {
+        value := ""
+        if i+1 < len(labels) {
+            value = labels[i+1]
+        }
+        pairs = append(pairs, sanitizeName(labels[i])+`="`+escapeLabel(value)+`"`)
+    }
+    sort.Strings(pairs)
+    return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func withLabel(labels, key, value string) string {
+    pair := key + `="` + value + `"`
+    if labels == "" {
+        return "{" + pair + "}"
+    }
+    return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+// sanitizeName replaces the characters Prometheus does not allow in metric
+// and label names with underscores.
+func sanitizeName(name string) string {
+    var b strings.Builder
+    for i, r := range name {
+        switch {
+        case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+            b.WriteRune(r)
+        case r >= '0' && r <= '9' && i > 0:
+            b.WriteRune(r)
+        default:
+            b.WriteRune('_')
+        }
+    }
+    return b.String()
+}
+
+func escapeLabel(value string) string {
+    value = strings.ReplaceAll(value, `\`, `\\`)
+    value = strings.ReplaceAll(value, `"`, `\"`)
+    return strings.ReplaceAll(value, "\n", `\n`)
+}
+
+func escapeHelp(help string) string {
+    help = strings.ReplaceAll(help, `\`, `\\`)
+    return strings.ReplaceAll(help, "\n", `\n`)
+}
+
+func formatValue(value float64) string {
+    switch {
+    case math.IsInf(value, 1):
+        return "+Inf"
+    case math.IsInf(value, -1):
+        return "-Inf"
+    }
+    return strconv.FormatFloat(value, 'g', -1, 64)
+}
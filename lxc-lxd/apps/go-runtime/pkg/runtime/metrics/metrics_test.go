@@ -0,0 +1,62 @@
+package metrics
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestWriteTo(t *testing.T) {
+    r := NewRegistry()
+    r.NewCounter("orders_total", "Orders by status.")
+    r.AddCounter("orders_total", 2, "status", "paid")
+    r.AddCounter("orders_total", 1, "status", "failed")
+    r.SetGauge("queue_depth", 3)
+    r.NewHistogram("latency_seconds", "", []float64{0.1, 1})
+    r.ObserveHistogram("latency_seconds", 0.5)
+
+    var buf bytes.Buffer
+    if _, err := r.WriteTo(&buf); err != nil {
+        t.Fatal(err)
+    }
+    want := `# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 0
+latency_seconds_bucket{le="1"} 1
+latency_seconds_bucket{le="+Inf"} 1
+latency_seconds_sum 0.5
+latency_seconds_count 1
+# HELP orders_total Orders by status.
+# TYPE orders_total counter
+orders_total{status="failed"} 1
+orders_total{status="paid"} 2
+# TYPE queue_depth gauge
+queue_depth 3
+`
+    if got := buf.String(); got != want {
+        t.Errorf("WriteTo() =\n%s\nwant\n%s", got, want)
+    }
+}
+
+func TestWriteToGaugeFuncUsingRegistry(t *testing.T) {
+    r := NewRegistry()
+    r.NewGaugeFunc("scrapes", "", func() float64 {
+        r.AddCounter("gauge_calls_total", 1)
+        return 1
+    })
+
+    done := make(chan string)
+    go func() {
+        var buf bytes.Buffer
+        r.WriteTo(&buf)
+        done <- buf.String()
+    }()
+    select {
+    case out := <-done:
+        if !strings.Contains(out, "scrapes 1\n") {
+            t.Errorf("WriteTo() = %s, want the gauge value", out)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("WriteTo deadlocked on a gauge function using the registry")
+    }
+}
@@ -0,0 +1,265 @@
+// Package redact masks secrets before they reach logs and error messages.
+//
+// Two kinds of secrets are masked: values registered with AddSecrets, which
+// includes the values of all environment variables with a sensitive name,
+// and the value of any `name=value`, `name: value` or `"name": "value"` pair
+// whose name is sensitive, e.g. `--token=...`, `X-CFF-Secret-Key: ...` or
+// `"password": "..."`, as well as Kubernetes style `name: ...` / `value: ...`
+// entries. Quoted values are masked up to the closing quote, unquoted values
+// up to the end of the line when the pair starts the line, e.g. in an env
+// listing or a YAML document, and up to the next space otherwise.
+package redact
+
+import (
+    "context"
+    "encoding/json"
+    "log/slog"
+    "os"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+)
+
+// Mask replaces every redacted value.
+const Mask = "******"
+
+// minSecretLength keeps short values such as "1" or "on" from being masked
+// wherever they appear.
+const minSecretLength = 4
+
+// quotedValue matches a double or single quoted value, including the quotes.
+const quotedValue = `"(?:[^"\\]|\\.)*"|'[^']*'`
+
+var (
+    mu      sync.RWMutex
+    secrets = map[string]struct{}{}
+    ordered []string
+    envOnce sync.Once
+
+    // linePattern matches a pair starting a line, whose unquoted value runs
+    // to the end of the line.
+    linePattern = regexp.MustCompile(`(?m)^[ \t]*(?:-[ \t]+|export[ \t]+)?(?P<name>[A-Za-z][A-Za-z0-9_.-]*)[ \t]*[:=][ \t]*(?P<value>[^\s"'\[{][^\r\n]*)`)
+    // envVarPattern matches the name and value entries of an environment
+    // variable in a Kubernetes manifest, in YAML or JSON.
+    envVarPattern = regexp.MustCompile(`\bname"?[ \t]*:[ \t]*["']?(?P<name>[A-Za-z][A-Za-z0-9_.-]*)["']?[ \t]*,?\s*(?:-[ \t]+)?"?value"?[ \t]*:[ \t]*(?P<value>` + quotedValue + `|[^\s"'\[{][^\r\n,}]*)`)
+    pairPattern   = regexp.MustCompile(`(?P<name>[A-Za-z][A-Za-z0-9_.-]*)"?\s*[:=]\s*\[?(?P<value>` + quotedValue + `|(?:(?:Bearer|Basic)\s+)?[^\s"',}\]&]+)`)
+
+    sensitiveWords = map[string]bool{
+        "password": true, "passwd": true, "pwd": true, "secret": true,
+        "token": true, "sk": true, "authorization": true, "credential": true,
+        "credentials": true, "apikey": true,
+    }
+)
+
+// AddSecrets registers values to be masked wherever they appear for the
+// life of the process, e.g. the passwords an app loads from its
+// configuration. Empty and very short values are ignored. Values that change
+// per invocation, such as temporary keys, belong in a Scrubber instead.
+func AddSecrets(values ...string) {
+    mu.Lock()
+    defer mu.Unlock()
+    for _, value := range values {
+        if len(value) < minSecretLength {
+            continue
+        }
+        if _, ok := secrets[value]; ok {
+            continue
+        }
+        secrets[value] = struct{}{}
+        ordered = append(ordered, value)
+    }
+    sortLongestFirst(ordered)
+}
+
+// sortLongestFirst orders secrets so a secret containing another one is
+// masked whole.
+func sortLongestFirst(values []string) {
+    sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+}
+
+// Scrubber masks a few extra values on top of the registered secrets, e.g.
+// the temporary keys of one invocation. It is meant to live as long as the
+// values it holds.
+type Scrubber struct {
+    values []string
+}
+
+// NewScrubber returns a Scrubber masking values. Empty and very short values
+// are ignored.
+func NewScrubber(values ...string) Scrubber {
+    var kept []string
+    for _, value := range values {
+        if len(value) >= minSecretLength {
+            kept = append(kept, value)
+        }
+    }
+    sortLongestFirst(kept)
+    return Scrubber{values: kept}
+}
+
+// String returns s with the values of the Scrubber and all secrets masked.
+func (sc Scrubber) String(s string) string {
+    return String(maskSecrets(s, sc.values))
+}
+
+// maskSecrets masks every value of secrets, also where it appears escaped
+// inside a JSON string.
+func maskSecrets(s string, secrets []string) string {
+    for _, secret := range secrets {
+        if !strings.Contains(s, secret) {
+            if escaped := jsonEscape(secret); escaped != secret && strings.Contains(s, escaped) {
+                s = strings.ReplaceAll(s, escaped, Mask)
+            }
+            continue
+        }
+        s = strings.ReplaceAll(s, secret, Mask)
+    }
+    return s
+}
+
+// LoadEnvSecrets registers the values of the environment variables with a
+// sensitive name. It runs on first use of String and should be called again
+// after the environment changes, e.g. after loading a .env file.
+func LoadEnvSecrets() {
+    var values []string
+    for _, entry := range os.Environ() {
+        name, value, ok := strings.Cut(entry, "=")
+        if ok && IsSensitiveName(name) {
+            values = append(values, value)
+        }
+    }
+    AddSecrets(values...)
+}
+
+// IsSensitiveName reports whether a header, environment variable, flag or
+// field called name holds a secret, e.g. SECRET_KEY, X-CFF-Security-Token,
+// --token or db_root_password.
+func IsSensitiveName(name string) bool {
+    // The shell's PWD and OLDPWD hold directories, masking them would mask
+    // every path below the working directory.
+    if strings.EqualFold(name, "PWD") || strings.EqualFold(name, "OLDPWD") {
+        return false
+    }
+    words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+        return r == '_' || r == '-' || r == '.'
+    })
+    for _, word := range words {
+        if sensitiveWords[word] || strings.HasSuffix(word, "password") || strings.HasSuffix(word, "secret") {
+            return true
+        }
+    }
+    return false
+}
+
+// String returns s with all secrets masked.
+func String(s string) string {
+    envOnce.Do(LoadEnvSecrets)
+
+    mu.RLock()
+    s = maskSecrets(s, ordered)
+    mu.RUnlock()
+
+    for _, pattern := range []*regexp.Regexp{linePattern, envVarPattern, pairPattern} {
+        s = maskValues(pattern, s)
+    }
+    return s
+}
+
+// maskValues masks the value group of every match of pattern whose name
+// group is sensitive. The quotes around a quoted value are kept.
+func maskValues(pattern *regexp.Regexp, s string) string {
+    nameGroup, valueGroup := pattern.SubexpIndex("name"), pattern.SubexpIndex("value")
+
+    var b strings.Builder
+    last := 0
+    for _, match := range pattern.FindAllStringSubmatchIndex(s, -1) {
+        name := s[match[2*nameGroup]:match[2*nameGroup+1]]
+        start, end := match[2*valueGroup], match[2*valueGroup+1]
+        value := s[start:end]
+
+        quote := ""
+        if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+            quote = value[:1]
+            value = value[1 : len(value)-1]
+        }
+        if !IsSensitiveName(name) || value == Mask || value == "" {
+            continue
+        }
+        b.WriteString(s[last:start])
+        b.WriteString(quote + Mask + quote)
+        last = end
+    }
+    if last == 0 {
+        return s
+    }
+    b.WriteString(s[last:])
+    return b.String()
+}
+
+// jsonEscape returns secret as it appears inside a JSON string.
+func jsonEscape(secret string) string {
+    data, err := json.Marshal(secret)
+    if err != nil {
+        return secret
+    }
+    return string(data[1 : len(data)-1])
+}
+
+// Handler is a slog.Handler that masks secrets in the message and the
+// string attributes of every record before passing it on.
+type Handler struct {
+    next slog.Handler
+}
+
+func NewHandler(next slog.Handler) *Handler {
+    return &Handler{next: next}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+    return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+    redacted := slog.NewRecord(record.Time, record.Level, String(record.Message), record.PC)
+    record.Attrs(func(attr slog.Attr) bool {
+        redacted.AddAttrs(redactAttr(attr))
+        return true
+    })
+    return h.next.Handle(ctx, redacted)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+    redacted := make([]slog.Attr, 0, len(attrs))
+    for _, attr := range attrs {
+        redacted = append(redacted, redactAttr(attr))
+    }
+    return &Handler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+    return &Handler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+    value := attr.Value.Resolve()
+    switch value.Kind() {
+    case slog.KindString:
+        if IsSensitiveName(attr.Key) && value.String() != "" {
+            return slog.String(attr.Key, Mask)
+        }
+        return slog.String(attr.Key, String(value.String()))
+    case slog.KindGroup:
+        group := value.Group()
+        redacted := make([]any, 0, len(group))
+        for _, member := range group {
+            redacted = append(redacted, redactAttr(member))
+        }
+        return slog.Group(attr.Key, redacted...)
+    case slog.KindAny:
+        if err, ok := value.Any().(error); ok {
+            return slog.String(attr.Key, String(err.Error()))
+        }
+    }
+    return slog.Attr{Key: attr.Key, Value: value}
+}
@@ -0,0 +1,154 @@
+package redact
+
+import (
+    "bytes"
+    "log/slog"
+    "strings"
+    "testing"
+)
+
+func TestString(t *testing.T) {
+    tests := []struct {
+        name string
+        in   string
+        want string
+    }{
+        {
+            name: "flag",
+            in:   "kubectl --server=https://cci --token=abc.def.ghi apply -f -",
+            want: "kubectl --server=https://cci --token=****** apply -f -",
+        },
+        {
+            name: "header",
+            in:   "X-CFF-Secret-Key: sk-1234567",
+            want: "X-CFF-Secret-Key: ******",
+        },
+        {
+            name: "bearer header",
+            in:   "curl -H Authorization: Bearer abc.def -X GET",
+            want: "curl -H Authorization: ****** -X GET",
+        },
+        {
+            name: "json",
+            in:   `{"user": "admin", "password": "p w x", "port": 3306}`,
+            want: `{"user": "admin", "password": "******", "port": 3306}`,
+        },
+        {
+            name: "json escaped quote",
+            in:   `{"db_root_password":"a\"b c"}`,
+            want: `{"db_root_password":"******"}`,
+        },
+        {
+            name: "single quoted",
+            in:   "ansible_password='abc def' ansible_user=root",
+            want: "ansible_password='******' ansible_user=root",
+        },
+        {
+            name: "env line",
+            in:   "ANSIBLE_USER=root\nANSIBLE_PASSWORD=abc def\nDB_ROOT_HOST=localhost",
+            want: "ANSIBLE_USER=root\nANSIBLE_PASSWORD=******\nDB_ROOT_HOST=localhost",
+        },
+        {
+            name: "yaml plain scalar",
+            in:   "db:\n  password: p w x\n  user: root",
+            want: "db:\n  password: ******\n  user: root",
+        },
+        {
+            name: "yaml name value",
+            in:   "        - name: ANSIBLE_USER\n          value: \"root\"\n        - name: ANSIBLE_PASSWORD\n          value: \"abc def\"\n        - name: SECRET_KEY\n          value: plain secret\n",
+            want: "        - name: ANSIBLE_USER\n          value: \"root\"\n        - name: ANSIBLE_PASSWORD\n          value: \"******\"\n        - name: SECRET_KEY\n          value: ******\n",
+        },
+        {
+            name: "json name value",
+            in:   `[{"name": "DB_ROOT_PASSWORD", "value": "abc def"}, {"name": "DB_ROOT_USER", "value": "root"}]`,
+            want: `[{"name": "DB_ROOT_PASSWORD", "value": "******"}, {"name": "DB_ROOT_USER", "value": "root"}]`,
+        },
+        {
+            name: "working directory",
+            in:   "open /srv/function/config.yaml: no such file\nPWD=/srv/function OLDPWD=/srv",
+            want: "open /srv/function/config.yaml: no such file\nPWD=/srv/function OLDPWD=/srv",
+        },
+        {
+            name: "not sensitive",
+            in:   "subdomain=demo theme: twentytwentyfour",
+            want: "subdomain=demo theme: twentytwentyfour",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := String(tt.in)
+            if got != tt.want {
+                t.Errorf("String(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+            }
+            if again := String(got); again != got {
+                t.Errorf("String is not idempotent: %q became %q", got, again)
+            }
+        })
+    }
+}
+
+func TestAddSecrets(t *testing.T) {
+    AddSecrets("s3cr3t-value", "ab")
+
+    got := String(`connect failed for s3cr3t-value, "ab" was ignored`)
+    want := `connect failed for ******, "ab" was ignored`
+    if got != want {
+        t.Errorf("got %q, want %q", got, want)
+    }
+}
+
+func TestIsSensitiveName(t *testing.T) {
+    for name, want := range map[string]bool{
+        "SECRET_KEY":           true,
+        "X-CFF-Security-Token": true,
+        "--token":              true,
+        "db_root_password":     true,
+        "DBPASSWORD":           true,
+        "db_pwd":               true,
+        "PWD":                  false,
+        "OLDPWD":               false,
+        "ACCESS_KEY":           false,
+        "username":             false,
+    } {
+        if got := IsSensitiveName(name); got != want {
+            t.Errorf("IsSensitiveName(%q) = %t, want %t", name, got, want)
+        }
+    }
+}
+
+func TestLoadEnvSecretsKeepsWorkingDirectory(t *testing.T) {
+    t.Setenv("PWD", "/srv/function-pwd-test")
+    LoadEnvSecrets()
+
+    in := "panic at /srv/function-pwd-test/main.go:28"
+    if got := String(in); got != in {
+        t.Errorf("String(%q) = %q, want it unchanged", in, got)
+    }
+}
+
+func TestHandler(t *testing.T) {
+    var buf bytes.Buffer
+    logger := slog.New(NewHandler(slog.NewTextHandler(&buf, nil)))
+
+    logger.Info("running kubectl --token=abc.def", slog.String("secret_key", "xyz"), slog.Group("db", slog.String("dsn", "password=hunter22")))
+
+    out := buf.String()
+    for _, leaked := range []string{"abc.def", "xyz", "hunter22"} {
+        if strings.Contains(out, leaked) {
+            t.Errorf("log output leaks %q: %s", leaked, out)
+        }
+    }
+}
+
+func TestScrubber(t *testing.T) {
+    scrubber := NewScrubber("tmp-sk-0001", "tok", "")
+
+    got := scrubber.String(`apply failed for tmp-sk-0001 with {"key":"tmp-sk-0001"}, tok stays`)
+    want := `apply failed for ****** with {"key":"******"}, tok stays`
+    if got != want {
+        t.Errorf("got %q, want %q", got, want)
+    }
+    if got := String("tmp-sk-0001"); got != "tmp-sk-0001" {
+        t.Errorf("scrubber value leaked into the registered secrets: %q", got)
+    }
+}
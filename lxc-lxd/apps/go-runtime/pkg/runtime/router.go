@@ -0,0 +1,164 @@
+package runtime
+
+import (
+    stdcontext "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+
+    "huaweicloud.com/go-runtime/events/apig"
+    "huaweicloud.com/go-runtime/events/cts"
+    "huaweicloud.com/go-runtime/events/dds"
+    "huaweicloud.com/go-runtime/events/dis"
+    "huaweicloud.com/go-runtime/events/kafka"
+    "huaweicloud.com/go-runtime/events/lts"
+    "huaweicloud.com/go-runtime/events/smn"
+    "huaweicloud.com/go-runtime/events/timer"
+    "huaweicloud.com/go-runtime/go-api/context"
+    "huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
+)
+
+// TriggerType identifies the event source that invoked the function.
+type TriggerType string
+
+const (
+    TriggerUnknown TriggerType = ""
+    TriggerAPIG    TriggerType = "APIG"
+    TriggerSMN     TriggerType = "SMN"
+    TriggerTimer   TriggerType = "TIMER"
+    TriggerKafka   TriggerType = "KAFKA"
+    TriggerDIS     TriggerType = "DIS"
+    TriggerLTS     TriggerType = "LTS"
+    TriggerCTS     TriggerType = "CTS"
+    TriggerDDS     TriggerType = "DDS"
+
+    ErrorTypeUnsupportedTrigger = "UnsupportedTrigger"
+)
+
+// DetectTrigger sniffs the top-level fields of payload to find which event
+// source sent it. It returns TriggerUnknown for payloads that are not a JSON
+// object or do not match any known shape.
+func DetectTrigger(payload []byte) TriggerType {
+    var fields map[string]json.RawMessage
+    if err := json.Unmarshal(payload, &fields); err != nil {
+        return TriggerUnknown
+    }
+    has := func(keys ...string) bool {
+        for _, key := range keys {
+            if _, ok := fields[key]; !ok {
+                return false
+            }
+        }
+        return true
+    }
+
+    switch {
+    case has("record"):
+        return TriggerSMN
+    case has("records", "instance_id"):
+        return TriggerKafka
+    case has("records"):
+        return TriggerDDS
+    case has("httpMethod"):
+        return TriggerAPIG
+    case has("trigger_type", "trigger_name"):
+        return TriggerTimer
+    case has("lts"):
+        return TriggerLTS
+    case has("cts"):
+        return TriggerCTS
+    case has("ShardID"), has("StreamName"):
+        return TriggerDIS
+    }
+    return TriggerUnknown
+}
+
+// Router dispatches each invocation to the handler registered for its
+// trigger, so one function can serve several triggers, e.g. an APIG health
+// endpoint and an SMN subscription. Router implements
+// fnhandler.IRequestHandler and is registered with RegisterHandler.
+type Router struct {
+    handlers map[TriggerType]fnhandler.IRequestHandler
+    fallback fnhandler.IRequestHandler
+}
+
+func NewRouter() *Router {
+    return &Router{handlers: make(map[TriggerType]fnhandler.IRequestHandler)}
+}
+
+// Route registers handler for trigger, replacing any previous one.
+func (r *Router) Route(trigger TriggerType, handler fnhandler.IRequestHandler) *Router {
+    r.handlers[trigger] = handler
+    return r
+}
+
+// Default registers the handler used for payloads whose trigger has no
+// handler. Without one such payloads are rejected with a 400 UserError.
+func (r *Router) Default(handler fnhandler.IRequestHandler) *Router {
+    r.fallback = handler
+    return r
+}
+
+func (r *Router) Handle(payload []byte, ctx context.RuntimeContext) (interface{}, error) {
+    trigger := DetectTrigger(payload)
+    ctx.GetLogger().Debugf("detected trigger %q", trigger)
+
+    handler, ok := r.handlers[trigger]
+    if !ok {
+        handler = r.fallback
+    }
+    if handler == nil {
+        if trigger == TriggerUnknown {
+            return nil, fnhandler.NewUserError(http.StatusBadRequest, ErrorTypeUnsupportedTrigger, "payload does not match any known trigger event", nil)
+        }
+        return nil, fnhandler.NewUserError(http.StatusBadRequest, ErrorTypeUnsupportedTrigger, fmt.Sprintf("no handler registered for %s trigger", trigger), nil)
+    }
+    return handler.Handle(payload, ctx)
+}
+
+func OnAPIG[R any](r *Router, handler fnhandler.TypedHandlerFunc[apig.APIGTriggerEvent, R]) *Router {
+    return r.Route(TriggerAPIG, fnhandler.NewTypedHandler(handler))
+}
+
+// OnHTTP routes APIG events to a plain net/http handler, see RegisterHTTP.
+func OnHTTP(r *Router, handler http.Handler) *Router {
+    return OnAPIG(r, httpHandlerFunc(handler))
+}
+
+func OnSMN[R any](r *Router, handler fnhandler.TypedHandlerFunc[smn.SMNTriggerEvent, R]) *Router {
+    return r.Route(TriggerSMN, fnhandler.NewTypedHandler(handler))
+}
+
+func OnTimer[R any](r *Router, handler fnhandler.TypedHandlerFunc[timer.TimerTriggerEvent, R]) *Router {
+    return r.Route(TriggerTimer, fnhandler.NewTypedHandler(handler))
+}
+
+func OnKafka[R any](r *Router, handler fnhandler.TypedHandlerFunc[kafka.KAFKATriggerEvent, R]) *Router {
+    return r.Route(TriggerKafka, fnhandler.NewTypedHandler(handler))
+}
+
+func OnDIS[R any](r *Router, handler fnhandler.TypedHandlerFunc[dis.DISTriggerEvent, R]) *Router {
+    return r.Route(TriggerDIS, fnhandler.NewTypedHandler(handler))
+}
+
+func OnLTS[R any](r *Router, handler fnhandler.TypedHandlerFunc[lts.LTSTriggerEvent, R]) *Router {
+    return r.Route(TriggerLTS, fnhandler.NewTypedHandler(handler))
+}
+
+func OnCTS[R any](r *Router, handler fnhandler.TypedHandlerFunc[cts.CTSTriggerEvent, R]) *Router {
+    return r.Route(TriggerCTS, fnhandler.NewTypedHandler(handler))
+}
+
+func OnDDS[R any](r *Router, handler fnhandler.TypedHandlerFunc[dds.DDSTriggerEvent, R]) *Router {
+    return r.Route(TriggerDDS, fnhandler.NewTypedHandler(handler))
+}
+
+func httpHandlerFunc(handler http.Handler) fnhandler.TypedHandlerFunc[apig.APIGTriggerEvent, apig.APIGTriggerResponse] {
+    return func(ctx stdcontext.Context, event apig.APIGTriggerEvent, _ context.RuntimeContext) (apig.APIGTriggerResponse, error) {
+        resp, err := apig.ServeHTTP(ctx, handler, &event)
+        if err != nil {
+            return resp, fnhandler.NewUserError(http.StatusBadRequest, fnhandler.ErrorTypeValidation, err.Error(), nil)
+        }
+        return resp, nil
+    }
+}
@@ -0,0 +1,194 @@
+package runtime
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "net/rpc"
+    "os"
+    "strconv"
+    "time"
+
+    "huaweicloud.com/go-runtime/pkg/runtime/fnhandler"
+    "huaweicloud.com/go-runtime/pkg/runtime/httptransport"
+    "huaweicloud.com/go-runtime/pkg/runtime/metrics"
+)
+
+const (
+    defaultShutdownGracePeriod = 30 * time.Second
+
+    // TransportRPC serves net/rpc gob-over-HTTP, as the FunctionGraph host expects.
+    TransportRPC = "rpc"
+    // TransportHTTP serves POST /invoke and GET /health, see package httptransport.
+    TransportHTTP = "http"
+
+    // MetricsPath is where the metrics listener serves metrics.Default.
+    MetricsPath = "/metrics"
+)
+
+// ShutdownHook releases resources held by the function, such as SMTP or
+// database connections. Hooks run after in-flight invocations have drained.
+type ShutdownHook func(ctx context.Context) error
+
+var shutdownHooks []ShutdownHook
+
+// OnShutdown registers hook to run when the runtime shuts down. It must be
+// called before Register.
+func OnShutdown(hook ShutdownHook) {
+    shutdownHooks = append(shutdownHooks, hook)
+}
+
+// Server serves a Function over net/rpc and shuts it down gracefully.
+type Server struct {
+    Addr string
+    // Transport is TransportRPC or TransportHTTP.
+    Transport string
+    // GracePeriod bounds how long Shutdown waits for in-flight invocations
+    // and shutdown hooks.
+    GracePeriod time.Duration
+    Hooks       []ShutdownHook
+    // MetricsAddr, when set, serves metrics.Default at /metrics on a
+    // separate listener.
+    MetricsAddr string
+
+    function *fnhandler.Function
+}
+
+// NewServer returns a server for function listening on RUNTIME_API_ADDR with
+// the transport selected by RUNTIME_TRANSPORT (defaults to rpc), the grace
+// period read from RUNTIME_SHUTDOWN_GRACE_PERIOD (a duration such as "30s",
+// or a number of seconds), the metrics listener address read from
+// RUNTIME_METRICS_ADDR and the hooks registered with OnShutdown.
+func NewServer(function *fnhandler.Function) *Server {
+    transport := os.Getenv("RUNTIME_TRANSPORT")
+    if transport == "" {
+        transport = TransportRPC
+    }
+    return &Server{
+        Addr:        os.Getenv("RUNTIME_API_ADDR"),
+        Transport:   transport,
+        GracePeriod: shutdownGracePeriodFromEnv(),
+        Hooks:       append([]ShutdownHook(nil), shutdownHooks...),
+        MetricsAddr: os.Getenv("RUNTIME_METRICS_ADDR"),
+        function:    function,
+    }
+}
+
+func shutdownGracePeriodFromEnv() time.Duration {
+    value := os.Getenv("RUNTIME_SHUTDOWN_GRACE_PERIOD")
+    if value == "" {
+        return defaultShutdownGracePeriod
+    }
+    if period, err := time.ParseDuration(value); err == nil {
+        return period
+    }
+    if seconds, err := strconv.Atoi(value); err == nil {
+        return time.Duration(seconds) * time.Second
+    }
+    log.Printf("env 'RUNTIME_SHUTDOWN_GRACE_PERIOD'(%s) invalid.", value)
+    return defaultShutdownGracePeriod
+}
+
+// ListenAndServe listens on s.Addr and calls Serve.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+    listener, err := net.Listen("tcp", s.Addr)
+    if err != nil {
+        return fmt.Errorf("listen on runtime address %q failed: %w", s.Addr, err)
+    }
+    return s.Serve(ctx, listener)
+}
+
+// Serve serves invocations from listener until ctx is done, then stops
+// accepting invocations, waits for in-flight ones and runs the shutdown
+// hooks, all within s.GracePeriod.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+    handler, err := s.newHandler()
+    if err != nil {
+        listener.Close()
+        return err
+    }
+    httpServer := &http.Server{Handler: handler}
+
+    var metricsServer *http.Server
+    if s.MetricsAddr != "" {
+        metricsListener, err := net.Listen("tcp", s.MetricsAddr)
+        if err != nil {
+            listener.Close()
+            return fmt.Errorf("listen on metrics address %q failed: %w", s.MetricsAddr, err)
+        }
+        mux := http.NewServeMux()
+        mux.Handle(MetricsPath, metrics.Default.Handler())
+        metricsServer = &http.Server{Handler: mux}
+        go func() {
+            if err := metricsServer.Serve(metricsListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+                log.Printf("metrics listener stopped: %s", err)
+            }
+        }()
+        defer metricsServer.Close()
+    }
+
+    serveErr := make(chan error, 1)
+    go func() {
+        serveErr <- httpServer.Serve(listener)
+    }()
+
+    select {
+    case err := <-serveErr:
+        return err
+    case <-ctx.Done():
+    }
+
+    return s.shutdown(httpServer)
+}
+
+func (s *Server) newHandler() (http.Handler, error) {
+    switch s.Transport {
+    case TransportRPC, "":
+        rpcServer := rpc.NewServer()
+        if err := rpcServer.Register(s.function); err != nil {
+            return nil, fmt.Errorf("failed to register handler function: %w", err)
+        }
+        mux := http.NewServeMux()
+        mux.Handle(rpc.DefaultRPCPath, rpcServer)
+        return mux, nil
+    case TransportHTTP:
+        return httptransport.NewHandler(s.function), nil
+    default:
+        return nil, fmt.Errorf("unknown runtime transport %q, expected %q or %q", s.Transport, TransportRPC, TransportHTTP)
+    }
+}
+
+func (s *Server) shutdown(httpServer *http.Server) error {
+    ctx, cancel := context.WithTimeout(context.Background(), s.GracePeriod)
+    defer cancel()
+
+    // net/rpc hijacks its connections, so Shutdown only stops the listener
+    // there and the in-flight invocations are tracked by the function itself.
+    err := httpServer.Shutdown(ctx)
+    return errors.Join(err, s.drain(ctx))
+}
+
+// Shutdown stops the function from accepting invocations, waits for the
+// in-flight ones and runs the shutdown hooks, all within s.GracePeriod, for a
+// function invoked without Serve, e.g. in local mode.
+func (s *Server) Shutdown() error {
+    ctx, cancel := context.WithTimeout(context.Background(), s.GracePeriod)
+    defer cancel()
+    return s.drain(ctx)
+}
+
+func (s *Server) drain(ctx context.Context) error {
+    var errs []error
+    if err := s.function.Drain(ctx); err != nil {
+        errs = append(errs, err)
+    }
+    for _, hook := range s.Hooks {
+        if err := hook(ctx); err != nil {
+            errs = append(errs, fmt.Errorf("shutdown hook failed: %w", err))
+        }
+    }
+    return errors.Join(errs...)
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+    "context"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/propagation"
+    "go.opentelemetry.io/otel/trace"
+
+    "huaweicloud.com/go-runtime/events/smn"
+)
+
+// InjectSMN adds the trace context of ctx to attributes, the
+// message_attributes of a message about to be published to SMN, and returns
+// them. A nil attributes map is allocated.
+func InjectSMN(ctx context.Context, attributes map[string]string) map[string]string {
+    if attributes == nil {
+        attributes = map[string]string{}
+    }
+    otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(attributes))
+    return attributes
+}
+
+// ExtractSMN returns ctx carrying the trace context found in the message
+// attributes of body, so spans started from it continue the publisher's
+// trace. ctx is returned unchanged when the message carries none.
+func ExtractSMN(ctx context.Context, body *smn.SMNBody) context.Context {
+    if len(body.MessageAttributes) == 0 {
+        return ctx
+    }
+    return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(body.MessageAttributes))
+}
+
+// StartSMNRecord starts the consumer span of one SMN message. It continues
+// the publisher's trace when the message carries one and links back to the
+// invocation span of ctx; otherwise it is a child of the invocation span.
+// End it with EndSpan.
+func StartSMNRecord(ctx context.Context, body *smn.SMNBody) (context.Context, trace.Span) {
+    options := []trace.SpanStartOption{
+        trace.WithSpanKind(trace.SpanKindConsumer),
+        trace.WithAttributes(
+            attribute.String("messaging.system", "smn"),
+            attribute.String("messaging.operation.type", "process"),
+            attribute.String("messaging.destination.name", body.TopicUrn),
+            attribute.String("messaging.message.id", body.MessageId),
+        ),
+    }
+    parent := ExtractSMN(ctx, body)
+    if invocation := trace.SpanContextFromContext(ctx); invocation.IsValid() && !invocation.Equal(trace.SpanContextFromContext(parent)) {
+        options = append(options, trace.WithLinks(trace.Link{SpanContext: invocation}))
+    }
+    return Tracer().Start(parent, "smn process", options...)
+}
+
+// EndSpan marks span as failed when err is not nil and ends it.
+func EndSpan(span trace.Span, err error) {
+    if err != nil {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+    }
+    span.End()
+}
+
+// TraceParent returns the W3C traceparent of ctx, e.g. for the TRACEPARENT
+// environment variable of a job started on behalf of the invocation. It is
+// empty when ctx carries no trace.
+func TraceParent(ctx context.Context) string {
+    carrier := propagation.MapCarrier{}
+    propagation.TraceContext{}.Inject(ctx, carrier)
+    return carrier.Get("traceparent")
+}
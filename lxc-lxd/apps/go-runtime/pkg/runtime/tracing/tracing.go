@@ -0,0 +1,189 @@
+// Package tracing instruments invocations with OpenTelemetry spans and
+// carries trace context across SMN, so one hosting order can be followed
+// from the web form through every function it triggers.
+//
+// Every invocation gets a server span. Its parent comes from W3C
+// traceparent headers when the caller sent them; otherwise the trace ID is
+// derived from X-CFF-Request-Id, so a trace can be looked up by request ID.
+package tracing
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "net/http"
+    "os"
+    "strings"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+    "go.opentelemetry.io/otel/propagation"
+    "go.opentelemetry.io/otel/sdk/resource"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of the runtime's spans.
+const TracerName = "huaweicloud.com/go-runtime"
+
+const (
+    headerRequestID  = "X-CFF-Request-Id"
+    headerInvokeType = "X-CFF-Invoke-Type"
+
+    exporterOTLP = "otlp"
+    exporterNone = "none"
+)
+
+func init() {
+    otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Tracer returns the tracer for spans started by handlers.
+func Tracer() trace.Tracer {
+    return otel.Tracer(TracerName)
+}
+
+// Setup installs the global tracer provider from the environment. Spans are
+// exported over OTLP/HTTP when OTEL_TRACES_EXPORTER is "otlp" or an
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set;
+// the exporter reads the other standard OTEL_EXPORTER_OTLP_* variables
+// itself. Otherwise nothing is installed and only incoming trace context is
+// propagated. The returned function flushes pending spans.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+    if !otlpEnabled() {
+        return func(context.Context) error { return nil }, nil
+    }
+    exporter, err := otlptracehttp.New(ctx)
+    if err != nil {
+        return nil, err
+    }
+    res, err := newResource(ctx)
+    if err != nil {
+        return nil, err
+    }
+    provider := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(exporter),
+        sdktrace.WithResource(res),
+        sdktrace.WithIDGenerator(requestIDGenerator{}),
+    )
+    otel.SetTracerProvider(provider)
+    return provider.Shutdown, nil
+}
+
+// SetupWithExporter installs a global tracer provider that hands every span
+// to exporter as soon as it ends, e.g. tracetest.NewInMemoryExporter() in
+// tests. The returned function shuts the provider down.
+func SetupWithExporter(exporter sdktrace.SpanExporter) func(context.Context) error {
+    res, _ := newResource(context.Background())
+    provider := sdktrace.NewTracerProvider(
+        sdktrace.WithSyncer(exporter),
+        sdktrace.WithResource(res),
+        sdktrace.WithIDGenerator(requestIDGenerator{}),
+    )
+    otel.SetTracerProvider(provider)
+    return provider.Shutdown
+}
+
+func otlpEnabled() bool {
+    switch strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER")) {
+    case exporterOTLP:
+        return true
+    case exporterNone:
+        return false
+    }
+    return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// newResource describes the function; OTEL_SERVICE_NAME and
+// OTEL_RESOURCE_ATTRIBUTES take precedence.
+func newResource(ctx context.Context) (*resource.Resource, error) {
+    name := os.Getenv("RUNTIME_FUNC_NAME")
+    if name == "" {
+        name = "unknown_function"
+    }
+    return resource.New(ctx,
+        resource.WithAttributes(
+            attribute.String("service.name", name),
+            attribute.String("service.version", os.Getenv("RUNTIME_FUNC_VERSION")),
+            attribute.String("faas.name", name),
+            attribute.String("faas.version", os.Getenv("RUNTIME_FUNC_VERSION")),
+            attribute.String("cloud.provider", "huawei_cloud"),
+        ),
+        resource.WithTelemetrySDK(),
+        resource.WithFromEnv(),
+    )
+}
+
+// StartInvocation starts the server span of one invocation described by
+// header. End it with EndInvocation.
+func StartInvocation(ctx context.Context, header http.Header) (context.Context, trace.Span) {
+    ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+    requestID := header.Get(headerRequestID)
+    if !trace.SpanContextFromContext(ctx).IsValid() && requestID != "" {
+        ctx = context.WithValue(ctx, traceIDSeedKey{}, TraceIDFromRequestID(requestID))
+    }
+    invokeType := header.Get(headerInvokeType)
+    if invokeType == "" {
+        invokeType = "sync"
+    }
+    return Tracer().Start(ctx, "invoke",
+        trace.WithSpanKind(trace.SpanKindServer),
+        trace.WithAttributes(
+            attribute.String("faas.invocation_id", requestID),
+            attribute.String("faas.invoke_type", invokeType),
+        ),
+    )
+}
+
+// EndInvocation records the outcome of the invocation on span and ends it.
+func EndInvocation(span trace.Span, outcome, errorType string, err error) {
+    span.SetAttributes(attribute.String("faas.outcome", outcome))
+    if err != nil {
+        span.SetAttributes(attribute.String("error.type", errorType))
+        span.RecordError(err)
+        span.SetStatus(codes.Error, errorType)
+    }
+    span.End()
+}
+
+type traceIDSeedKey struct{}
+
+// TraceIDFromRequestID returns the trace ID of an invocation without an
+// incoming trace context: the request ID itself when it is a UUID, as
+// FunctionGraph request IDs are, otherwise a hash of it.
+func TraceIDFromRequestID(requestID string) trace.TraceID {
+    var traceID trace.TraceID
+    raw := strings.ReplaceAll(requestID, "-", "")
+    if decoded, err := hex.DecodeString(raw); err == nil && len(decoded) == len(traceID) {
+        copy(traceID[:], decoded)
+        if traceID.IsValid() {
+            return traceID
+        }
+    }
+    sum := sha256.Sum256([]byte(requestID))
+    copy(traceID[:], sum[:])
+    return traceID
+}
+
+// requestIDGenerator uses the trace ID seeded by StartInvocation for new
+// root spans and random IDs for everything else.
+type requestIDGenerator struct{}
+
+func (requestIDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+    traceID, ok := ctx.Value(traceIDSeedKey{}).(trace.TraceID)
+    if !ok {
+        rand.Read(traceID[:])
+    }
+    return traceID, requestIDGenerator{}.NewSpanID(ctx, traceID)
+}
+
+func (requestIDGenerator) NewSpanID(context.Context, trace.TraceID) trace.SpanID {
+    var spanID trace.SpanID
+    for !spanID.IsValid() {
+        rand.Read(spanID[:])
+    }
+    return spanID
+}
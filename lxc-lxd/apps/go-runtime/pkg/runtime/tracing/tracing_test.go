@@ -0,0 +1,146 @@
+package tracing
+
+import (
+    "context"
+    "crypto/sha256"
+    "errors"
+    "net/http"
+    "testing"
+
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/sdk/trace/tracetest"
+    "go.opentelemetry.io/otel/trace"
+
+    "huaweicloud.com/go-runtime/events/smn"
+)
+
+// setupInMemory installs a tracer provider recording into an in-memory
+// exporter for the duration of the test.
+func setupInMemory(t *testing.T) *tracetest.InMemoryExporter {
+    exporter := tracetest.NewInMemoryExporter()
+    shutdown := SetupWithExporter(exporter)
+    t.Cleanup(func() { shutdown(context.Background()) })
+    return exporter
+}
+
+func attributeValue(attrs []attribute.KeyValue, key string) string {
+    for _, attr := range attrs {
+        if string(attr.Key) == key {
+            return attr.Value.Emit()
+        }
+    }
+    return ""
+}
+
+func TestStartInvocationSeedsTraceID(t *testing.T) {
+    exporter := setupInMemory(t)
+
+    header := http.Header{}
+    header.Set(headerRequestID, "0af7651916cd43dd8448eb211c80319c")
+    header.Set(headerInvokeType, "async")
+    _, span := StartInvocation(context.Background(), header)
+    EndInvocation(span, "error", "FunctionTimeout", errors.New("timed out"))
+
+    spans := exporter.GetSpans()
+    if len(spans) != 1 {
+        t.Fatalf("exported %d spans, want 1", len(spans))
+    }
+    got := spans[0]
+    if got.SpanContext.TraceID().String() != "0af7651916cd43dd8448eb211c80319c" {
+        t.Errorf("trace ID = %s, want the request ID", got.SpanContext.TraceID())
+    }
+    if got.SpanKind != trace.SpanKindServer || got.Parent.IsValid() {
+        t.Errorf("span kind %s with parent %v, want a root server span", got.SpanKind, got.Parent)
+    }
+    if id := attributeValue(got.Attributes, "faas.invocation_id"); id != "0af7651916cd43dd8448eb211c80319c" {
+        t.Errorf("faas.invocation_id = %q", id)
+    }
+    if invokeType := attributeValue(got.Attributes, "faas.invoke_type"); invokeType != "async" {
+        t.Errorf("faas.invoke_type = %q, want async", invokeType)
+    }
+    if got.Status.Code != codes.Error || attributeValue(got.Attributes, "error.type") != "FunctionTimeout" {
+        t.Errorf("status %v, error.type %q, want the error recorded", got.Status, attributeValue(got.Attributes, "error.type"))
+    }
+}
+
+func TestStartInvocationHashesOtherRequestIDs(t *testing.T) {
+    exporter := setupInMemory(t)
+
+    header := http.Header{}
+    header.Set(headerRequestID, "local-request")
+    _, span := StartInvocation(context.Background(), header)
+    span.End()
+
+    sum := sha256.Sum256([]byte("local-request"))
+    var want trace.TraceID
+    copy(want[:], sum[:])
+    if got := exporter.GetSpans()[0].SpanContext.TraceID(); got != want {
+        t.Errorf("trace ID = %s, want %s", got, want)
+    }
+    if TraceIDFromRequestID("local-request") != want {
+        t.Error("TraceIDFromRequestID disagrees with the span")
+    }
+}
+
+func TestStartInvocationContinuesTraceparent(t *testing.T) {
+    exporter := setupInMemory(t)
+
+    header := http.Header{}
+    header.Set(headerRequestID, "0af7651916cd43dd8448eb211c80319c")
+    header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+    _, span := StartInvocation(context.Background(), header)
+    span.End()
+
+    got := exporter.GetSpans()[0]
+    if got.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+        t.Errorf("trace ID = %s, want the one from traceparent", got.SpanContext.TraceID())
+    }
+    if got.Parent.SpanID().String() != "00f067aa0ba902b7" || !got.Parent.IsRemote() {
+        t.Errorf("parent = %v, want the remote span from traceparent", got.Parent)
+    }
+}
+
+func TestSMNRoundTrip(t *testing.T) {
+    exporter := setupInMemory(t)
+
+    // The publisher, e.g. the web form, injects its span into the message.
+    publishCtx, publishSpan := Tracer().Start(context.Background(), "publish")
+    attributes := InjectSMN(publishCtx, nil)
+    publishSpan.End()
+    if attributes["traceparent"] != TraceParent(publishCtx) || attributes["traceparent"] == "" {
+        t.Fatalf("message attributes %v do not carry the traceparent %q", attributes, TraceParent(publishCtx))
+    }
+
+    body := &smn.SMNBody{TopicUrn: "urn:smn:topic", MessageId: "m1", MessageAttributes: attributes}
+    extracted := trace.SpanContextFromContext(ExtractSMN(context.Background(), body))
+    if extracted.TraceID() != publishSpan.SpanContext().TraceID() || extracted.SpanID() != publishSpan.SpanContext().SpanID() {
+        t.Fatalf("extracted %v, want the publisher span %v", extracted, publishSpan.SpanContext())
+    }
+    if unchanged := ExtractSMN(publishCtx, &smn.SMNBody{}); unchanged != publishCtx {
+        t.Error("ExtractSMN changed ctx for a message without trace context")
+    }
+
+    // The subscriber processes it within its own invocation.
+    header := http.Header{}
+    header.Set(headerRequestID, "5b8aa5a2d2c872e8321cf37308d69df2")
+    invokeCtx, invokeSpan := StartInvocation(context.Background(), header)
+    _, recordSpan := StartSMNRecord(invokeCtx, body)
+    EndSpan(recordSpan, nil)
+    invokeSpan.End()
+
+    spans := exporter.GetSpans()
+    record := spans[len(spans)-2]
+    if record.Name != "smn process" || record.SpanKind != trace.SpanKindConsumer {
+        t.Fatalf("span %q of kind %s, want the smn process consumer span", record.Name, record.SpanKind)
+    }
+    if record.Parent.SpanID() != publishSpan.SpanContext().SpanID() {
+        t.Errorf("record span parent = %s, want the publisher span", record.Parent.SpanID())
+    }
+    if len(record.Links) != 1 || record.Links[0].SpanContext.SpanID() != invokeSpan.SpanContext().SpanID() {
+        t.Errorf("record span links = %v, want a link to the invocation span", record.Links)
+    }
+    if id := attributeValue(record.Attributes, "messaging.message.id"); id != "m1" {
+        t.Errorf("messaging.message.id = %q, want m1", id)
+    }
+}
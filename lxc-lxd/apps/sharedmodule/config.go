@@ -2,6 +2,11 @@ package sharedmodule
 
 import "os"
 
+// GetEnv returns the value of the environment variable key, or defaultValue
+// when it is not set.
+//
+// Deprecated: declare the configuration as a struct and load it with package
+// sharedmodule/config, which also validates and reports every invalid value.
 func GetEnv(key, defaultValue string) string {
 	if value, exist := os.LookupEnv(key); exist {
 		return value
@@ -0,0 +1,267 @@
+// Package config populates a configuration struct from environment variables
+// described by struct tags:
+//
+//	type Config struct {
+//		Host     string        `env:"SMTP_HOST" required:"true"`
+//		Port     int           `env:"SMTP_PORT" default:"587"`
+//		Password string        `env:"SMTP_PASSWORD" required:"true" secret:"true"`
+//		Timeout  time.Duration `env:"SMTP_TIMEOUT" default:"30s"`
+//		Admins   []string      `env:"ADMINS" sep:","`
+//	}
+//
+// Supported field types are strings, bools, ints, uints, floats,
+// time.Duration and slices of those, split on sep (default ","). Nested
+// structs without an env tag are populated field by field. Secret fields are
+// masked by Fprint and Masked.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tagEnv      = "env"
+	tagDefault  = "default"
+	tagRequired = "required"
+	tagSecret   = "secret"
+	tagSep      = "sep"
+
+	defaultSep = ","
+)
+
+// ErrMissing is the error of a required variable that is not set.
+var ErrMissing = errors.New("required but not set")
+
+// FieldError describes the variable of one field that could not be loaded.
+type FieldError struct {
+	Field string
+	Env   string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s (%s): %s", e.Env, e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors lists every variable that could not be loaded.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(messages, "; "))
+}
+
+// Unwrap lets errors.Is and errors.As look into every FieldError, e.g.
+// errors.Is(err, ErrMissing).
+func (e Errors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+type options struct {
+	dotEnv []string
+	lookup func(string) (string, bool)
+}
+
+// Option configures Load.
+type Option func(*options)
+
+// WithDotEnv loads the given .env files, ".env" when none is given, before
+// reading the environment, see LoadDotEnv. Missing files are skipped.
+func WithDotEnv(paths ...string) Option {
+	return func(o *options) {
+		if len(paths) == 0 {
+			paths = []string{".env"}
+		}
+		o.dotEnv = append(o.dotEnv, paths...)
+	}
+}
+
+// WithLookup reads variables with lookup instead of os.LookupEnv.
+func WithLookup(lookup func(string) (string, bool)) Option {
+	return func(o *options) {
+		o.lookup = lookup
+	}
+}
+
+// Load populates the struct pointed to by v. A variable set to the empty
+// string counts as not set, so its default applies. Every missing required
+// or unparsable variable is reported at once in an Errors; the other fields
+// are still populated.
+func Load(v any, opts ...Option) error {
+	o := &options{lookup: os.LookupEnv}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var errs Errors
+	for _, path := range o.dotEnv {
+		if err := LoadDotEnv(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, &FieldError{Field: "-", Env: path, Err: err})
+		}
+	}
+
+	fields, err := structFields(v)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		value, _ := o.lookup(f.env)
+		if value == "" {
+			if f.required {
+				errs = append(errs, &FieldError{Field: f.name, Env: f.env, Err: ErrMissing})
+				continue
+			}
+			value = f.def
+		}
+		if err := setValue(f.value, value, f.sep); err != nil {
+			errs = append(errs, &FieldError{Field: f.name, Env: f.env, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type field struct {
+	name     string
+	env      string
+	def      string
+	required bool
+	secret   bool
+	sep      string
+	value    reflect.Value
+}
+
+func structFields(v any) ([]field, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: %T is not a pointer to a struct", v)
+	}
+	var fields []field
+	collectFields(rv.Elem(), "", &fields)
+	return fields, nil
+}
+
+func collectFields(rv reflect.Value, prefix string, fields *[]field) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := prefix + sf.Name
+		env, ok := sf.Tag.Lookup(tagEnv)
+		if !ok {
+			if sf.Type.Kind() == reflect.Struct && sf.Type != reflect.TypeOf(time.Time{}) {
+				collectFields(rv.Field(i), name+".", fields)
+			}
+			continue
+		}
+		sep, ok := sf.Tag.Lookup(tagSep)
+		if !ok {
+			sep = defaultSep
+		}
+		*fields = append(*fields, field{
+			name:     name,
+			env:      env,
+			def:      sf.Tag.Get(tagDefault),
+			required: sf.Tag.Get(tagRequired) == "true",
+			secret:   sf.Tag.Get(tagSecret) == "true",
+			sep:      sep,
+			value:    rv.Field(i),
+		})
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, s, sep string) error {
+	if v.Kind() == reflect.Slice {
+		if s == "" {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+			return nil
+		}
+		parts := strings.Split(s, sep)
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setScalar(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setScalar(v, s)
+}
+
+func setScalar(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	}
+	if s == "" {
+		v.SetZero()
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Host     string        `env:"SMTP_HOST" required:"true"`
+	Port     int           `env:"SMTP_PORT" default:"587"`
+	Password string        `env:"SMTP_PASSWORD" secret:"true"`
+	TLS      bool          `env:"SMTP_TLS" default:"true"`
+	Timeout  time.Duration `env:"SMTP_TIMEOUT" default:"30s"`
+	Admins   []string      `env:"ADMINS"`
+	Ports    []int         `env:"PORTS" sep:";" default:"80;443"`
+	DB       struct {
+		User string `env:"DB_USER" default:"root"`
+	}
+}
+
+func lookupMap(env map[string]string) Option {
+	return WithLookup(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+}
+
+func TestLoad(t *testing.T) {
+	defaults := testConfig{Host: "smtp.example.com", Port: 587, TLS: true, Timeout: 30 * time.Second, Ports: []int{80, 443}, Admins: []string{}}
+	defaults.DB.User = "root"
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    func(c *testConfig)
+		wantErr []string
+	}{
+		{
+			name: "defaults",
+			env:  map[string]string{"SMTP_HOST": "smtp.example.com"},
+		},
+		{
+			name: "empty value uses default",
+			env:  map[string]string{"SMTP_HOST": "smtp.example.com", "SMTP_PORT": "", "SMTP_TIMEOUT": "", "PORTS": ""},
+		},
+		{
+			name: "values",
+			env: map[string]string{
+				"SMTP_HOST":     "mail",
+				"SMTP_PORT":     "25",
+				"SMTP_PASSWORD": "s3cret",
+				"SMTP_TLS":      "false",
+				"SMTP_TIMEOUT":  "1m",
+				"DB_USER":       "wordpress",
+			},
+			want: func(c *testConfig) {
+				c.Host, c.Port, c.Password, c.TLS, c.Timeout = "mail", 25, "s3cret", false, time.Minute
+				c.DB.User = "wordpress"
+			},
+		},
+		{
+			name: "lists",
+			env:  map[string]string{"SMTP_HOST": "smtp.example.com", "ADMINS": "a@example.com, b@example.com", "PORTS": "8080;8443"},
+			want: func(c *testConfig) {
+				c.Admins = []string{"a@example.com", "b@example.com"}
+				c.Ports = []int{8080, 8443}
+			},
+		},
+		{
+			name:    "required missing",
+			env:     map[string]string{},
+			wantErr: []string{"SMTP_HOST"},
+		},
+		{
+			name:    "required empty",
+			env:     map[string]string{"SMTP_HOST": ""},
+			wantErr: []string{"SMTP_HOST"},
+		},
+		{
+			name:    "invalid values",
+			env:     map[string]string{"SMTP_HOST": "smtp.example.com", "SMTP_PORT": "abc", "SMTP_TLS": "maybe", "SMTP_TIMEOUT": "10", "PORTS": "80;x"},
+			wantErr: []string{"SMTP_PORT", "SMTP_TLS", "SMTP_TIMEOUT", "PORTS"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testConfig
+			err := Load(&got, lookupMap(tt.env))
+			if len(tt.wantErr) > 0 {
+				var errs Errors
+				if !errors.As(err, &errs) {
+					t.Fatalf("Load() error = %v, want Errors", err)
+				}
+				var envs []string
+				for _, fieldErr := range errs {
+					envs = append(envs, fieldErr.Env)
+				}
+				if !reflect.DeepEqual(envs, tt.wantErr) {
+					t.Errorf("Load() failed for %v, want %v", envs, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			want := defaults
+			if tt.want != nil {
+				tt.want(&want)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Load() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadMissingIsErrMissing(t *testing.T) {
+	var c testConfig
+	err := Load(&c, lookupMap(nil))
+	if !errors.Is(err, ErrMissing) {
+		t.Errorf("Load() error = %v, want ErrMissing", err)
+	}
+}
+
+func TestLoadNotStructPointer(t *testing.T) {
+	var c testConfig
+	if err := Load(c); err == nil {
+		t.Error("Load() of a struct value succeeded")
+	}
+}
+
+func TestParseDotEnvValue(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "plain", want: "plain"},
+		{in: "plain # comment", want: "plain"},
+		{in: "a#b", want: "a#b"},
+		{in: `"double quoted # not a comment"`, want: "double quoted # not a comment"},
+		{in: `"line\nbreak"`, want: "line\nbreak"},
+		{in: `'single $quoted\n'`, want: `single $quoted\n`},
+		{in: `""`, want: ""},
+		{in: `"unterminated \"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDotEnvValue(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDotEnvValue(%s) error = %v, wantErr %t", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseDotEnvValue(%s) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "# comment\n\nexport CONFIG_TEST_A=\"a b\"\nCONFIG_TEST_B='c d' \nCONFIG_TEST_C=kept\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_TEST_C", "from env")
+	for _, key := range []string{"CONFIG_TEST_A", "CONFIG_TEST_B"} {
+		key := key
+		t.Cleanup(func() { os.Unsetenv(key) })
+	}
+
+	if err := LoadDotEnv(path); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"CONFIG_TEST_A": "a b", "CONFIG_TEST_B": "c d", "CONFIG_TEST_C": "from env"} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("NO_EQUALS\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDotEnv(path); err == nil {
+		t.Error("LoadDotEnv() of a line without = succeeded")
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadDotEnv sets the variables of a .env file that are not already set in
+// the environment. Lines are KEY=VALUE, optionally prefixed with "export";
+// blank lines and lines starting with # are skipped. Values may be single or
+// double quoted, double-quoted values support Go escapes such as \n.
+func LoadDotEnv(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNo)
+		}
+		value, err := parseDotEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		if _, exists := os.LookupEnv(key); !exists {
+			os.Setenv(key, value)
+		}
+	}
+	return scanner.Err()
+}
+
+func parseDotEnvValue(value string) (string, error) {
+	if len(value) >= 2 {
+		switch {
+		case value[0] == '"' && value[len(value)-1] == '"':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return "", fmt.Errorf("invalid quoted value %s", value)
+			}
+			return unquoted, nil
+		case value[0] == '\'' && value[len(value)-1] == '\'':
+			return value[1 : len(value)-1], nil
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+)
+
+// Mask replaces the value of secret fields.
+const Mask = "******"
+
+// Fprint writes the effective configuration v as KEY=value lines, with the
+// value of secret fields replaced by Mask.
+func Fprint(w io.Writer, v any) error {
+	fields, err := structFields(v)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", f.env, f.display()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Masked returns v as a slog value: a group with one attribute per variable,
+// secrets masked, e.g.
+//
+//	slog.Info("configuration loaded", slog.Any("config", config.Masked(&cfg)))
+func Masked(v any) slog.LogValuer {
+	return masked{v: v}
+}
+
+type masked struct {
+	v any
+}
+
+func (m masked) LogValue() slog.Value {
+	fields, err := structFields(m.v)
+	if err != nil {
+		return slog.StringValue(err.Error())
+	}
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, slog.String(f.env, f.display()))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// Secrets returns the non-empty values of the secret fields of v, e.g. to
+// register them with a log redactor.
+func Secrets(v any) []string {
+	fields, _ := structFields(v)
+	var secrets []string
+	for _, f := range fields {
+		if f.secret && !f.value.IsZero() {
+			secrets = append(secrets, f.format())
+		}
+	}
+	return secrets
+}
+
+func (f field) display() string {
+	if f.secret && !f.value.IsZero() {
+		return Mask
+	}
+	return f.format()
+}
+
+func (f field) format() string {
+	if f.value.Kind() == reflect.Slice {
+		parts := make([]string, f.value.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(f.value.Index(i).Interface())
+		}
+		return strings.Join(parts, f.sep)
+	}
+	return fmt.Sprint(f.value.Interface())
+}
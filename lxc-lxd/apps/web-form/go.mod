@@ -4,7 +4,6 @@ go 1.23.0
 
 require (
 	github.com/huaweicloud/huaweicloud-sdk-go-v3 v0.1.171
-	github.com/joho/godotenv v1.5.1
 	sharedmodule v0.0.0
 )
 
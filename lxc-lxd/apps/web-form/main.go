@@ -4,43 +4,42 @@ import (
 	"embed"
 	"log/slog"
 	"net/http"
-	"sharedmodule"
+	"os"
+	"sharedmodule/config"
 	"text/template"
 
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
 	smn "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/smn/v2"
 	smnRegion "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/smn/v2/region"
-	"github.com/joho/godotenv"
 )
 
 //go:embed templates/*
 var templateFiles embed.FS
 
 type Config struct {
-	AccessKey               string
-	SecretKey               string
-	SmnTopicNotificationUrn string
-	SmnTopicHostingUrn      string
-	RootDomainName          string
+	AccessKey               string `env:"ACCESS_KEY" required:"true"`
+	SecretKey               string `env:"SECRET_KEY" required:"true" secret:"true"`
+	SmnTopicNotificationUrn string `env:"SMN_TOPIC_NOTIFICATION_URN" default:"notification"`
+	SmnTopicHostingUrn      string `env:"SMN_TOPIC_HOSTING_URN" default:"newhosting"`
+	RootDomainName          string `env:"ROOT_DOMAIN_NAME" default:"onhuawei.cloud"`
 }
 
 var appConfig Config
 
-func loadConfig() {
-	if err := godotenv.Load(); err != nil {
-		slog.Warn("No .env file found")
+func loadConfig() error {
+	if err := config.Load(&appConfig, config.WithDotEnv()); err != nil {
+		return err
 	}
-
-	appConfig.AccessKey = sharedmodule.GetEnv("ACCESS_KEY", "")
-	appConfig.SecretKey = sharedmodule.GetEnv("SECRET_KEY", "")
-	appConfig.SmnTopicNotificationUrn = sharedmodule.GetEnv("SMN_TOPIC_NOTIFICATION_URN", "notification")
-	appConfig.SmnTopicHostingUrn = sharedmodule.GetEnv("SMN_TOPIC_HOSTING_URN", "newhosting")
-	appConfig.RootDomainName = sharedmodule.GetEnv("ROOT_DOMAIN_NAME", "onhuawei.cloud")
+	slog.Info("configuration loaded", slog.Any("config", config.Masked(&appConfig)))
+	return nil
 }
 
 func main() {
 
-	loadConfig()
+	if err := loadConfig(); err != nil {
+		slog.Error("failed to load configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
 	cred, err := basic.NewCredentialsBuilder().WithAk(appConfig.AccessKey).WithSk(appConfig.SecretKey).SafeBuild()
 	if err != nil {